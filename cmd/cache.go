@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	gnmatcher "github.com/gnames/gnmatcher/pkg"
+	gnmcnf "github.com/gnames/gnmatcher/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manages lookup data of gnmatcher.",
+	Long: `Manages lookup data (bloom filters, trie of stems, key-value stores
+of stems and epithets, and viruses data) located in the CacheDir.`,
+}
+
+// cacheBuildCmd represents the cache build command
+var cacheBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Builds lookup data from gnames database or a local dump.",
+	Long: `Builds lookup data from gnames database, or from a local dump if
+DumpDir is set. Existing data are replaced. By default all components
+are built, use --component flag to rebuild only some of them.
+
+Components: bloom, trie, stems-kv, epithets-kv, virus.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		if debug {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+		}
+		comps, _ := cmd.Flags().GetStringSlice("component")
+		components := make([]gnmatcher.CacheComponent, len(comps))
+		for i := range comps {
+			components[i] = gnmatcher.CacheComponent(comps[i])
+		}
+
+		cfg := gnmcnf.New(opts...)
+		start := time.Now()
+		if err := gnmatcher.BuildCache(cfg, components...); err != nil {
+			slog.Error("Cannot build cache", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Cache is built",
+			"path", cfg.CacheDir,
+			"elapsed", time.Since(start).Round(time.Second).String(),
+		)
+	},
+}
+
+var cacheUpdateCmd = &cobra.Command{
+	Use:   "update <delta-dir>",
+	Short: "Adds new names from a delta to existing lookup data.",
+	Long: `Adds new canonical forms from a delta to existing lookup data
+without a full rebuild. The delta is a directory with canonicals.tsv and
+name_string_indices.tsv files in the format of a dump of gnames data.
+
+The command cannot run while the REST service uses the same cache, use
+'POST /api/v1/admin/update' endpoint of the service instead.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		if debug {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+		}
+
+		cfg := gnmcnf.New(opts...)
+		gnm := gnmatcher.New(cfg)
+		if err := gnm.Init(); err != nil {
+			slog.Error("Error initializing matcher", "error", err)
+			os.Exit(1)
+		}
+		num, err := gnm.Update(args[0])
+		if err != nil {
+			slog.Error("Cannot update cache", "error", err)
+			os.Exit(1)
+		}
+		if err = gnm.Close(); err != nil {
+			slog.Error("Cannot close matcher", "error", err)
+		}
+		slog.Info("Cache is updated", "path", cfg.CacheDir, "newStemsNum", num)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheBuildCmd)
+	cacheCmd.AddCommand(cacheUpdateCmd)
+
+	cacheBuildCmd.Flags().StringSliceP(
+		"component", "c", nil,
+		"build only given components (bloom, trie, stems-kv, epithets-kv, virus)",
+	)
+	cacheBuildCmd.Flags().BoolP("debug", "d", false, "set logs level to DEBUG")
+	cacheUpdateCmd.Flags().BoolP("debug", "d", false, "set logs level to DEBUG")
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gnames/gnlib/ent/nomcode"
+	"github.com/gnames/gnmatcher/internal/io/namesio"
+	gnmatcher "github.com/gnames/gnmatcher/pkg"
+	gnmcnf "github.com/gnames/gnmatcher/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// maxBatch is the largest number of names that MatchNames processes at once.
+const maxBatch = 10_000
+
+// matchCmd represents the match command
+var matchCmd = &cobra.Command{
+	Use:   "match [file]",
+	Short: "Matches name-strings from a file or STDIN.",
+	Long: `Matches name-strings from a file, or from STDIN if the file is not
+given or is '-', and writes results to STDOUT.
+
+The input contains one name-string per line, or it is a CSV/TSV file with
+a header. Files with '.csv' and '.tsv' extensions are read as CSV/TSV,
+use --input flag to set the input format explicitly. Name-strings are
+taken from the first column, or from the column set by --column flag.
+
+Results are written as CSV, TSV (a row for every match item), JSON or
+JSON lines (an object for every name-string).`,
+	Args:          cobra.MaximumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		debug, _ := cmd.Flags().GetBool("debug")
+		if debug {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+		}
+
+		path := "-"
+		if len(args) > 0 {
+			path = args[0]
+		}
+		r, inFmt, err := matchInput(cmd, path)
+		if err != nil {
+			return fmt.Errorf("cannot open input: %w", err)
+		}
+		defer r.Close()
+
+		column, _ := cmd.Flags().GetString("column")
+		names, err := namesio.NewReader(r, inFmt, column)
+		if err != nil {
+			return fmt.Errorf("cannot read input: %w", err)
+		}
+
+		s, _ := cmd.Flags().GetString("format")
+		outFmt, err := namesio.NewFormat(s)
+		if err != nil {
+			return fmt.Errorf("cannot set output format: %w", err)
+		}
+
+		batch, _ := cmd.Flags().GetInt("batch")
+		if batch < 1 || batch > maxBatch {
+			slog.Warn("Batch size is out of range, using maximum",
+				"batch", batch, "max", maxBatch)
+			batch = maxBatch
+		}
+
+		cfg := gnmcnf.New(opts...)
+		gnm := gnmatcher.New(cfg)
+		if err = gnm.Init(); err != nil {
+			return fmt.Errorf("cannot initialize matcher: %w", err)
+		}
+		defer func() {
+			if err := gnm.Close(); err != nil {
+				slog.Error("Cannot close matcher", "error", err)
+			}
+		}()
+
+		ctx, stop := signal.NotifyContext(
+			context.Background(), os.Interrupt, syscall.SIGTERM,
+		)
+		defer stop()
+
+		w := namesio.NewWriter(os.Stdout, outFmt)
+		errsNum, err := matchNames(ctx, gnm, names, w, batch, matchOpts(cmd))
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("cannot write output: %w", cerr)
+		}
+		if err != nil {
+			return err
+		}
+		if errsNum > 0 {
+			return fmt.Errorf("matching failed for %d name(s)", errsNum)
+		}
+		return nil
+	},
+}
+
+// matchNames matches names from the reader by batches and writes the
+// results. Names that failed to match are logged, and their number is
+// returned. Matching stops on errors of reading, writing, or if the context
+// is canceled.
+func matchNames(
+	ctx context.Context,
+	gnm gnmatcher.GNmatcher,
+	names *namesio.Reader,
+	w *namesio.Writer,
+	batch int,
+	mOpts []gnmcnf.Option,
+) (int, error) {
+	var errsNum int
+	for {
+		batchNames, err := names.Batch(batch)
+		if len(batchNames) > 0 {
+			// per-name errors are given in the matches, and context error
+			// is checked after writing of partial results.
+			res, _ := gnm.MatchNamesDetailed(ctx, batchNames, mOpts...)
+			errsNum += res.ErrorsNum
+			if werr := w.Write(res.MatcherOutput().Matches); werr != nil {
+				return errsNum, fmt.Errorf("cannot write output: %w", werr)
+			}
+			if cerr := ctx.Err(); cerr != nil {
+				return errsNum, fmt.Errorf("matching was interrupted: %w", cerr)
+			}
+			for _, v := range res.Matches {
+				if v.Error != "" {
+					slog.Error("Cannot match name",
+						"name", v.Name, "error", v.Error)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return errsNum, nil
+		}
+		if err != nil {
+			return errsNum, fmt.Errorf("cannot read input: %w", err)
+		}
+	}
+}
+
+// matchInput opens the input and finds out its format.
+func matchInput(
+	cmd *cobra.Command,
+	path string,
+) (io.ReadCloser, namesio.InputFormat, error) {
+	s, _ := cmd.Flags().GetString("input")
+	f, err := namesio.NewInputFormat(s)
+	if err != nil {
+		return nil, "", err
+	}
+	if f == "" {
+		f = namesio.DetectInputFormat(path)
+	}
+
+	if path == "-" {
+		return io.NopCloser(os.Stdin), f, nil
+	}
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return r, f, nil
+}
+
+// matchOpts converts flags of the command to matching options.
+func matchOpts(cmd *cobra.Command) []gnmcnf.Option {
+	var res []gnmcnf.Option
+	if b, _ := cmd.Flags().GetBool("species-group"); b {
+		res = append(res, gnmcnf.OptWithSpeciesGroup(true))
+	}
+	if b, _ := cmd.Flags().GetBool("relaxed-fuzzy"); b {
+		res = append(res, gnmcnf.OptWithRelaxedFuzzyMatch(true))
+	}
+	if b, _ := cmd.Flags().GetBool("uninomial-fuzzy"); b {
+		res = append(res, gnmcnf.OptWithUninomialFuzzyMatch(true))
+	}
+	if b, _ := cmd.Flags().GetBool("batch-context"); b {
+		res = append(res, gnmcnf.OptWithBatchContext(true))
+	}
+	if b, _ := cmd.Flags().GetBool("ocr"); b {
+		res = append(res, gnmcnf.OptWithOCRDistance(true))
+	}
+	if b, _ := cmd.Flags().GetBool("adaptive-edit-dist"); b {
+		res = append(res, gnmcnf.OptWithAdaptiveEditDist(true))
+	}
+	if b, _ := cmd.Flags().GetBool("all-partials"); b {
+		res = append(res, gnmcnf.OptWithAllPartials(true))
+	}
+	if b, _ := cmd.Flags().GetBool("words-change"); b {
+		res = append(res, gnmcnf.OptWithWordsChange(true))
+	}
+	if b, _ := cmd.Flags().GetBool("recombinations"); b {
+		res = append(res, gnmcnf.OptWithRecombinations(true))
+	}
+	if s, _ := cmd.Flags().GetString("code"); s != "" {
+		res = append(res, gnmcnf.OptNomCode(nomcode.New(s)))
+	}
+	if ds, _ := cmd.Flags().GetIntSlice("data-sources"); len(ds) > 0 {
+		res = append(res, gnmcnf.OptDataSources(ds))
+	}
+	return res
+}
+
+func init() {
+	rootCmd.AddCommand(matchCmd)
+
+	matchCmd.Flags().StringP("format", "f", "csv",
+		"output format (csv, tsv, json, jsonl)")
+	matchCmd.Flags().StringP("input", "i", "",
+		"input format (lines, csv, tsv), detected from extension by default")
+	matchCmd.Flags().StringP("column", "c", "",
+		"name or number (starting from 1) of CSV/TSV column with names")
+	matchCmd.Flags().IntP("batch", "b", 5000,
+		"number of names sent to matching at once (max 10000)")
+	matchCmd.Flags().BoolP("species-group", "s", false,
+		"match species names to their species groups")
+	matchCmd.Flags().BoolP("relaxed-fuzzy", "r", false,
+		"use relaxed rules of fuzzy matching")
+	matchCmd.Flags().BoolP("uninomial-fuzzy", "u", false,
+		"allow fuzzy matching of uninomials")
+	matchCmd.Flags().BoolP("batch-context", "x", false,
+		"boost fuzzy matches with genera of exact matches in the same batch")
+	matchCmd.Flags().BoolP("ocr", "o", false,
+		"use weighted edit distance for typical OCR errors")
+	matchCmd.Flags().BoolP("adaptive-edit-dist", "a", false,
+		"max edit distance depends on the length of a name")
+	matchCmd.Flags().BoolP("all-partials", "p", false,
+		"return matches of all truncated versions of names")
+	matchCmd.Flags().BoolP("words-change", "w", false,
+		"match names with merged or split words")
+	matchCmd.Flags().BoolP("recombinations", "R", false,
+		"match names by epithets to names with other genera")
+	matchCmd.Flags().StringP("code", "C", "",
+		"nomenclatural code (botanical, zoological, bacterial, cultivars)")
+	matchCmd.Flags().IntSliceP("data-sources", "S", nil,
+		"limit matches to given data-source IDs")
+	matchCmd.Flags().BoolP("debug", "d", false, "set logs level to DEBUG")
+}
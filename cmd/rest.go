@@ -22,8 +22,12 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gnames/gnfmt"
 	"github.com/gnames/gnmatcher/internal/io/rest"
@@ -54,11 +58,27 @@ as well.`,
 			os.Exit(1)
 		}
 
+		go reloadOnSignal(gnm)
+
 		var enc gnfmt.Encoder = gnfmt.GNjson{}
 
+		ctx, stop := signal.NotifyContext(
+			context.Background(), os.Interrupt, syscall.SIGTERM,
+		)
+
 		service := rest.NewMatcherService(gnm, port, enc)
-		rest.Run(service)
-		os.Exit(0)
+		err := rest.Run(ctx, service)
+		stop()
+		if err != nil {
+			slog.Error("HTTP API server failed", "error", err)
+		}
+		if cerr := gnm.Close(); cerr != nil {
+			slog.Error("Cannot close matcher", "error", cerr)
+			err = cerr
+		}
+		if err != nil {
+			os.Exit(1)
+		}
 	},
 }
 
@@ -68,3 +88,21 @@ func init() {
 	restCmd.Flags().IntP("port", "p", 8080, "REST port")
 	restCmd.Flags().BoolP("debug", "d", false, "set logs level to DEBUG")
 }
+
+// reloadOnSignal reloads lookup data every time the process receives
+// SIGHUP signal.
+func reloadOnSignal(gnm gnmatcher.GNmatcher) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	for range ch {
+		slog.Info("Received SIGHUP, reloading lookup data")
+		err := gnm.Reload()
+		if errors.Is(err, gnmatcher.ErrBusy) {
+			slog.Warn("Cannot reload lookup data", "reason", err)
+			continue
+		}
+		if err != nil {
+			slog.Error("Cannot reload lookup data", "error", err)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/gnames/gnlib/ent/nomcode"
 	"github.com/gnames/gnsys"
 
 	"github.com/spf13/cobra"
@@ -29,14 +30,20 @@ var (
 // cfgData purpose is to achieve automatic import of data from the
 // configuration file, if it exists.
 type cfgData struct {
-	CacheDir    string
-	JobsNum     int
-	MaxEditDist int
-	PgHost      string
-	PgPort      int
-	PgUser      string
-	PgPass      string
-	PgDB        string
+	AdminToken         string
+	CacheDir           string
+	DumpDir            string
+	EditDistThresholds []config.EditDistThreshold
+	JobsNum            int
+	MaxEditDist        int
+	NomCode            string
+	OCRConfusions      []string
+	PgHost             string
+	PgPort             int
+	PgUser             string
+	PgPass             string
+	PgDB               string
+	QueueSize          int
 }
 
 // rootCmd represents the base command when called without any subcommands
@@ -56,7 +63,7 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	if err := rootCmd.Execute(); err != nil {
-		slog.Error("Cannot start gnmatcher", "error", err)
+		slog.Error("Cannot run gnmatcher", "error", err)
 		os.Exit(1)
 	}
 }
@@ -89,14 +96,19 @@ func initConfig() {
 
 	// Set environment variables to override
 	// config file settings
+	_ = viper.BindEnv("AdminToken", "GNM_ADMIN_TOKEN")
 	_ = viper.BindEnv("CacheDir", "GNM_CACHE_DIR")
+	_ = viper.BindEnv("DumpDir", "GNM_DUMP_DIR")
 	_ = viper.BindEnv("JobsNum", "GNM_JOBS_NUM")
 	_ = viper.BindEnv("MaxEditDist", "GNM_MAX_EDIT_DIST")
+	_ = viper.BindEnv("NomCode", "GNM_NOM_CODE")
+	_ = viper.BindEnv("OCRConfusions", "GNM_OCR_CONFUSIONS")
 	_ = viper.BindEnv("PgDB", "GNM_PG_DB")
 	_ = viper.BindEnv("PgHost", "GNM_PG_HOST")
 	_ = viper.BindEnv("PgPass", "GNM_PG_PASS")
 	_ = viper.BindEnv("PgPort", "GNM_PG_PORT")
 	_ = viper.BindEnv("PgUser", "GNM_PG_USER")
+	_ = viper.BindEnv("QueueSize", "GNM_QUEUE_SIZE")
 
 	viper.AutomaticEnv() // read in environment variables that match
 
@@ -120,15 +132,31 @@ func getOpts() []config.Option {
 		os.Exit(1)
 	}
 
+	if cfg.AdminToken != "" {
+		opts = append(opts, config.OptAdminToken(cfg.AdminToken))
+	}
 	if cfg.CacheDir != "" {
 		opts = append(opts, config.OptCacheDir(cfg.CacheDir))
 	}
+	if cfg.DumpDir != "" {
+		opts = append(opts, config.OptDumpDir(cfg.DumpDir))
+	}
+	if len(cfg.EditDistThresholds) > 0 {
+		opts = append(opts,
+			config.OptEditDistThresholds(cfg.EditDistThresholds))
+	}
 	if cfg.JobsNum > 0 {
 		opts = append(opts, config.OptJobsNum(cfg.JobsNum))
 	}
 	if cfg.MaxEditDist != 0 {
 		opts = append(opts, config.OptMaxEditDist(cfg.MaxEditDist))
 	}
+	if cfg.NomCode != "" {
+		opts = append(opts, config.OptNomCode(nomcode.New(cfg.NomCode)))
+	}
+	if len(cfg.OCRConfusions) > 0 {
+		opts = append(opts, config.OptOCRConfusions(cfg.OCRConfusions))
+	}
 	if cfg.PgDB != "" {
 		opts = append(opts, config.OptPgDB(cfg.PgDB))
 	}
@@ -144,6 +172,9 @@ func getOpts() []config.Option {
 	if cfg.PgUser != "" {
 		opts = append(opts, config.OptPgUser(cfg.PgUser))
 	}
+	if cfg.QueueSize > 0 {
+		opts = append(opts, config.OptQueueSize(cfg.QueueSize))
+	}
 	return opts
 }
 
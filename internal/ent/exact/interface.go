@@ -2,7 +2,9 @@
 // known scientific names.
 package exact
 
-import "github.com/gnames/gnmatcher/pkg/config"
+import (
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+)
 
 // ExactMatcher is the interface for exact matching strings.
 // It matches them using UUIDv5 strings generated from the strings.
@@ -11,11 +13,12 @@ type ExactMatcher interface {
 	// exist yet.
 	Init() error
 
-	// SetConfig updates configuration of the matcher.
-	SetConfig(config config.Config)
-
 	// MatchCanonicalID matches canonical forms of scientific names. It takes
 	// UUIDv5 filter generated out of name-string and checks if the same
 	// UUIDv5 exists in the cached data.
 	MatchCanonicalID(uuid string) bool
+
+	// AddStems adds stemmed canonical forms from the data provider to the
+	// lookup data, and saves updated data to the cache.
+	AddStems(data provider.DataProvider) error
 }
@@ -0,0 +1,17 @@
+package fuzzy
+
+import "strings"
+
+// SpecificEpithet returns the specific epithet of a stemmed canonical form.
+// It returns an empty string for uninomials and hybrid formulas, because
+// they are not in the index of epithets.
+func SpecificEpithet(stem string) string {
+	if strings.ContainsAny(stem, "×+") {
+		return ""
+	}
+	words := strings.Split(stem, " ")
+	if len(words) < 2 {
+		return ""
+	}
+	return words[1]
+}
@@ -44,6 +44,56 @@ func TestDist(t *testing.T) {
 	}
 }
 
+// EditDistanceReason explains failed checks
+func TestDistReason(t *testing.T) {
+	testData := []struct {
+		str1, str2, reason string
+		dist               int
+	}{
+		{"Pomatomus", "Pom-tomus", "", 1},
+		{"Acacia mal", "Acacia may", fuzzy.ReasonShortWord, -1},
+		{"Pomatomus", "Acacia", fuzzy.ReasonTooDistant, -1},
+	}
+
+	for _, v := range testData {
+		msg := fmt.Sprintf("'%s' vs '%s'", v.str1, v.str2)
+		dist, reason := fuzzy.EditDistanceReason(v.str1, v.str2, false)
+		assert.Equal(t, v.dist, dist, msg)
+		assert.Equal(t, v.reason, reason, msg)
+	}
+}
+
+// OCR distance makes typical OCR errors cheaper
+func TestOCRDist(t *testing.T) {
+	ocr := fuzzy.NewOCR(nil)
+	testData := []struct {
+		str1, str2 string
+		weighted   float64
+		dist       int
+	}{
+		{"Pardosa moesta", "Pardosa moesta", 0, 0},
+		{"Pardosa rnoesta", "Pardosa moesta", 0.5, 1},
+		{"Bubo buho", "Bubo bubo", 1, 1},
+		{"Acacia ma1", "Acacia mal", 0.5, 1},
+		{"Acacia mal", "Acacia may", 1, -1},
+		{"Pardosa clrnoesta", "Pardosa dmoesta", 1, 1},
+	}
+
+	for _, v := range testData {
+		msg := fmt.Sprintf("'%s' vs '%s'", v.str1, v.str2)
+		assert.Equal(t, v.weighted, ocr.Distance(v.str1, v.str2), msg)
+		dist, _ := ocr.EditDistanceReason(v.str1, v.str2, false)
+		assert.Equal(t, v.dist, dist, msg)
+	}
+
+	ocr = fuzzy.NewOCR([]string{"c:e", "wrong"})
+	assert.Equal(t, 0.5, ocr.Distance("Pardosa moesta", "Pardosa mocsta"))
+	assert.Equal(t, 2.0, ocr.Distance("Pardosa rnoesta", "Pardosa moesta"))
+
+	assert.Equal(t, []string{"Pardosa moest", "Pardosa rnocst"},
+		fuzzy.NewOCR([]string{"rn:m", "e:c"}).Variants("Pardosa rnoest")[:2])
+}
+
 // BenchmarkDist checks the speed of fuzzy matching. Run it with:
 // `go test -bench=. -benchmem -count=10 -run=XXX > bench.txt && benchstat bench.txt`
 
@@ -4,22 +4,27 @@ package fuzzy
 
 import (
 	mlib "github.com/gnames/gnlib/ent/matcher"
-	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
 )
 
+// Options are settings of a matching request that FuzzyMatcher needs.
+// They are given with every call, because the same FuzzyMatcher serves
+// concurrent requests with different settings.
+type Options struct {
+	// MaxEditDist is the maximal edit distance between stems.
+	MaxEditDist int
+}
+
 // FuzzyMatcher describes methods needed for fuzzy matching.
 type FuzzyMatcher interface {
 	// Initialize data for the matcher.
 	Init() error
 
-	// SetConfig updates configuration of the matcher.
-	SetConfig(cfg config.Config)
-
-	// MatchStem takes a stemmed scientific name and max edit distance.
-	// The search stops if current edit distance becomes bigger than edit
-	// distance. The method returns 0 or more stems that did match the
-	// input stem within the edit distance constraint.
-	MatchStem(stem string) []string
+	// MatchStem takes a stemmed scientific name and options with max edit
+	// distance. The search stops if current edit distance becomes bigger
+	// than edit distance. The method returns 0 or more stems that did match
+	// the input stem within the edit distance constraint.
+	MatchStem(stem string, opts Options) []string
 
 	// MatchStemExact takes a stem and returns true if the exact match of
 	// the stem is found.
@@ -28,4 +33,18 @@ type FuzzyMatcher interface {
 	// StemToCanonicals takes a stem and returns back canonicals
 	// that correspond to that stem.
 	StemToMatchItems(stem string) ([]mlib.MatchItem, error)
+
+	// EpithetToMatchItems takes a stemmed specific epithet and returns
+	// canonical forms with this epithet.
+	EpithetToMatchItems(epithet string) ([]mlib.MatchItem, error)
+
+	// SpeciesToMatchItems takes a stemmed binomial, like 'Aus bus', and
+	// returns canonical forms of its infraspecific stems, like 'Aus bus cus'.
+	SpeciesToMatchItems(species string) ([]mlib.MatchItem, error)
+
+	// AddStems adds stems with their canonical forms and data-sources from
+	// the data provider to the lookup data, and saves updated data to the
+	// cache. Canonical forms of known stems are merged with the new ones.
+	// It returns the number of stems that were not known before.
+	AddStems(data provider.DataProvider) (int, error)
 }
@@ -11,6 +11,16 @@ const (
 	maxEditDistance = 6
 )
 
+// Reasons why EditDistanceReason rejects a pair of strings.
+const (
+	// ReasonTooDistant means that the edit distance exceeds 6.
+	ReasonTooDistant = "edit distance is larger than 6"
+
+	// ReasonShortWord means that a word shorter than 5 characters has more
+	// than one edit per 4 characters.
+	ReasonShortWord = "too many edits in a short word"
+)
+
 // EditDistance calculates edit distance (**ed**) according to Levenshtein algorithm.
 // It also runs additional checks and if they fail, returns -1.
 //
@@ -32,32 +42,52 @@ const (
 // normalized to 1 space, and that s1 and s2 always have the same number of
 // words.
 func EditDistance(s1, s2 string, relax bool) int {
+	ed, _ := EditDistanceReason(s1, s2, relax)
+	return ed
+}
+
+// EditDistanceReason works like EditDistance, but if checks fail, it also
+// returns the reason of the failure.
+func EditDistanceReason(s1, s2 string, relax bool) (int, string) {
 	ed, _, _ := editdist.ComputeDistance(s1, s2, false)
 	if ed == 0 {
-		return ed
+		return ed, ""
 	}
 
 	if ed > maxEditDistance {
-		return -1
+		return -1, ReasonTooDistant
 	}
-	return checkED(s1, s2, ed, relax)
+	return checkED(s1, s2, ed, relax, levenshteinDist)
 }
 
-func checkED(s1, s2 string, ed int, relax bool) int {
+// levenshteinDist returns Levenshtein edit distance between two strings.
+func levenshteinDist(s1, s2 string) float64 {
+	ed, _, _ := editdist.ComputeDistance(s1, s2, false)
+	return float64(ed)
+}
+
+// checkED runs checks of EditDistance for the edit distance ed of s1 and
+// s2. The dist function calculates distances between words.
+func checkED(
+	s1, s2 string,
+	ed int,
+	relax bool,
+	dist func(string, string) float64,
+) (int, string) {
 	words1 := strings.Split(s1, " ")
 	words2 := strings.Split(s2, " ")
 	if len(words1) != len(words2) || (relax && len(words1) > 1) {
-		return ed
+		return ed, ""
 	}
 	for i, w := range words2 {
 		r := []rune(w)
 		// check short words if they do not have too many changes
 		if len(r) < 5 {
-			wordED, _, _ := editdist.ComputeDistance(w, words1[i], false)
-			if wordED > 0 && len(r)/wordED < charsPerED {
-				return -1
+			wordED := dist(w, words1[i])
+			if wordED > 0 && float64(len(r))/wordED < charsPerED {
+				return -1, ReasonShortWord
 			}
 		}
 	}
-	return ed
+	return ed, ""
 }
@@ -0,0 +1,143 @@
+package fuzzy
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+const (
+	// ocrCost is the cost of replacing one side of an OCR confusion with
+	// the other side.
+	ocrCost = 0.5
+
+	// maxOCRVariants limits the number of variants of a stem that are
+	// searched in the trie.
+	maxOCRVariants = 16
+)
+
+// ReasonOverMaxEditDist means that the weighted edit distance between stems
+// exceeds the maximal edit distance of the request.
+const ReasonOverMaxEditDist = "edit distance is larger than max edit distance"
+
+// DefaultOCRConfusions are character sequences that are often mistaken for
+// each other by optical character recognition. Every confusion has a
+// "from:to" format. Confusions are applied in both directions.
+var DefaultOCRConfusions = []string{
+	"rn:m", "cl:d", "li:h", "ii:u", "1:l", "0:o", "vv:w",
+}
+
+// ParseConfusion splits a confusion in "from:to" format into its sides.
+func ParseConfusion(s string) (string, string, error) {
+	from, to, ok := strings.Cut(s, ":")
+	if !ok || from == "" || to == "" || from == to {
+		return "", "", fmt.Errorf("confusion '%s' is not in 'from:to' format", s)
+	}
+	return from, to, nil
+}
+
+type confusion struct {
+	from, to []rune
+}
+
+// OCR is a weighted edit distance model for names that were digitized by
+// optical character recognition. Replacement of one side of a confusion by
+// the other costs half of an edit. For example distance between
+// 'Pardosa rnoesta' and 'Pardosa moesta' is 0.5 instead of 2. Other
+// changes cost one edit, as in Levenshtein distance.
+type OCR struct {
+	confusions []confusion
+}
+
+// NewOCR creates OCR model from confusions in "from:to" format. If
+// confusions are empty, DefaultOCRConfusions are used. Confusions in a
+// wrong format are ignored.
+func NewOCR(confusions []string) *OCR {
+	if len(confusions) == 0 {
+		confusions = DefaultOCRConfusions
+	}
+	res := &OCR{}
+	for _, v := range confusions {
+		from, to, err := ParseConfusion(v)
+		if err != nil {
+			continue
+		}
+		res.confusions = append(res.confusions,
+			confusion{from: []rune(from), to: []rune(to)},
+			confusion{from: []rune(to), to: []rune(from)},
+		)
+	}
+	return res
+}
+
+// Distance calculates weighted edit distance between two strings.
+func (o *OCR) Distance(s1, s2 string) float64 {
+	r1, r2 := []rune(s1), []rune(s2)
+	d := make([][]float64, len(r1)+1)
+	for i := range d {
+		d[i] = make([]float64, len(r2)+1)
+		d[i][0] = float64(i)
+	}
+	for j := range d[0] {
+		d[0][j] = float64(j)
+	}
+
+	for i := 1; i <= len(r1); i++ {
+		for j := 1; j <= len(r2); j++ {
+			sub := d[i-1][j-1]
+			if r1[i-1] != r2[j-1] {
+				sub++
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, sub)
+			for _, c := range o.confusions {
+				if hasSuffix(r1[:i], c.from) && hasSuffix(r2[:j], c.to) {
+					d[i][j] = min(d[i][j], d[i-len(c.from)][j-len(c.to)]+ocrCost)
+				}
+			}
+		}
+	}
+	return d[len(r1)][len(r2)]
+}
+
+// EditDistanceReason works like EditDistanceReason function, but uses
+// weighted distance of the model. The distance is rounded up, so any
+// difference between strings counts as at least one edit.
+func (o *OCR) EditDistanceReason(s1, s2 string, relax bool) (int, string) {
+	dist := o.Distance(s1, s2)
+	if dist == 0 {
+		return 0, ""
+	}
+
+	if dist > maxEditDistance {
+		return -1, ReasonTooDistant
+	}
+	ed := int(math.Ceil(dist))
+	return checkED(s1, s2, ed, relax, o.Distance)
+}
+
+// Variants returns strings made from s by fixing one of possible OCR
+// confusions. They are used to find candidates in the trie that are too
+// far from s by Levenshtein distance.
+func (o *OCR) Variants(s string) []string {
+	rs := []rune(s)
+	var res []string
+	for _, c := range o.confusions {
+		for i := 0; i+len(c.from) <= len(rs); i++ {
+			if !slices.Equal(rs[i:i+len(c.from)], c.from) {
+				continue
+			}
+			v := slices.Concat(rs[:i], c.to, rs[i+len(c.from):])
+			res = append(res, string(v))
+			if len(res) == maxOCRVariants {
+				return res
+			}
+		}
+	}
+	return res
+}
+
+func hasSuffix(rs, suffix []rune) bool {
+	return len(rs) >= len(suffix) &&
+		slices.Equal(rs[len(rs)-len(suffix):], suffix)
+}
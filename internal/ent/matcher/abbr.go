@@ -0,0 +1,221 @@
+package matcher
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/gnames/gnparser/ent/stemmer"
+	"github.com/gnames/gnuuid"
+	"github.com/gnames/levenshtein/ent/editdist"
+)
+
+// maxAbbrGenera is the maximal number of genera that are returned for an
+// abbreviated genus found by the epithet index. If there are more such
+// genera, the abbreviation cannot be resolved.
+const maxAbbrGenera = 10
+
+// batch contains data about all names of a MatchNames request, that help
+// to match individual names.
+type batch struct {
+	// genera are full genera that start names of the batch, sorted
+	// alphabetically.
+	genera []string
+}
+
+// newBatch collects genera from names of a batch. It does not parse names,
+// a genus is any capitalized first word that contains only letters.
+func newBatch(names []string) *batch {
+	var res batch
+	for _, name := range names {
+		genus, _, _ := strings.Cut(strings.TrimSpace(name), " ")
+		if isGenus(genus) {
+			res.genera = append(res.genera, genus)
+		}
+	}
+	slices.Sort(res.genera)
+	res.genera = slices.Compact(res.genera)
+	return &res
+}
+
+func isGenus(word string) bool {
+	rs := []rune(word)
+	if len(rs) < 2 || !unicode.IsUpper(rs[0]) {
+		return false
+	}
+	for _, r := range rs[1:] {
+		if !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// generaByPrefix returns genera of the batch that start with the prefix.
+func (b *batch) generaByPrefix(prefix string) []string {
+	if b == nil {
+		return nil
+	}
+	var res []string
+	idx, _ := slices.BinarySearch(b.genera, prefix)
+	for _, v := range b.genera[idx:] {
+		if !strings.HasPrefix(v, prefix) {
+			break
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// matchAbbreviated resolves an abbreviated genus of a name like
+// 'P. saltatrix'. First it tries full genera from the same batch of names
+// that start with the abbreviation. If they do not give a match, it
+// searches the epithet index for names with genera that start with the
+// abbreviation. Found items have the abbreviated canonical form as their
+// InputStr, and their edit distances count letters of the genus that were
+// restored, so Fuzzy match type of such items keeps its usual meaning.
+func (m matcher) matchAbbreviated(ns nameString) (*mlib.Match, error) {
+	abbr, _, _ := strings.Cut(ns.Canonical, " ")
+	prefix := strings.TrimSuffix(abbr, ".")
+	words := strings.Split(ns.CanonicalStem, " ")
+	if prefix == "" || len(words) < 2 {
+		return nil, nil
+	}
+
+	matchItems, err := m.matchAbbrBatch(ns, prefix, words[1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(matchItems) == 0 {
+		matchItems, err = m.matchAbbrEpithet(ns, prefix, words[1:])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(matchItems) == 0 {
+		return nil, nil
+	}
+
+	for i := range matchItems {
+		matchStr := matchItems[i].MatchStr
+		matchItems[i].InputStr = ns.Canonical
+		matchItems[i].EditDistance, _, _ = editdist.ComputeDistance(
+			ns.Canonical, matchStr, false,
+		)
+		matchItems[i].EditDistanceStem, _, _ = editdist.ComputeDistance(
+			ns.CanonicalStem, stemmer.StemCanonical(matchStr), false,
+		)
+		matchItems[i].MatchType = vlib.Fuzzy
+	}
+	return &mlib.Match{
+		ID:         ns.ID,
+		Name:       ns.Name,
+		MatchType:  vlib.Fuzzy,
+		MatchItems: matchItems,
+	}, nil
+}
+
+// matchAbbrBatch finds names that have the stemmed epithets of the input
+// and a genus from the batch that starts with the prefix.
+func (m matcher) matchAbbrBatch(
+	ns nameString,
+	prefix string,
+	epithets []string,
+) (res []mlib.MatchItem, err error) {
+	st := m.trace.begin(stageAbbrBatch, ns.CanonicalStem)
+	defer func() { st.end(&mlib.Match{MatchItems: res}) }()
+
+	for _, genus := range m.batch.generaByPrefix(prefix) {
+		stem := genus + " " + strings.Join(epithets, " ")
+		items, err := m.exactStemMatches(gnuuid.New(stem).String(), stem)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) > 0 {
+			st.candidates([]string{stem})
+		}
+		res = append(res, items...)
+	}
+	return m.filterDataSources(res, st), nil
+}
+
+// matchAbbrEpithet finds names with the stemmed epithets of the input in
+// the epithet index, and keeps the ones with a genus that starts with the
+// prefix.
+func (m matcher) matchAbbrEpithet(
+	ns nameString,
+	prefix string,
+	epithets []string,
+) (res []mlib.MatchItem, err error) {
+	st := m.trace.begin(stageAbbrEpithet, ns.CanonicalStem)
+	defer func() { st.end(&mlib.Match{MatchItems: res}) }()
+
+	epithet := fuzzy.SpecificEpithet(ns.CanonicalStem)
+	if epithet == "" {
+		return nil, nil
+	}
+	items, err := m.fuzzyMatcher.EpithetToMatchItems(epithet)
+	if err != nil {
+		return nil, err
+	}
+
+	genera := make(map[string]struct{})
+	for _, v := range items {
+		stem := stemmer.StemCanonical(v.MatchStr)
+		words := strings.Split(stem, " ")
+		if !strings.HasPrefix(words[0], prefix) ||
+			!slices.Equal(words[1:], epithets) {
+			continue
+		}
+		st.candidates([]string{stem})
+		genera[words[0]] = struct{}{}
+		res = append(res, v)
+	}
+	if len(genera) > maxAbbrGenera {
+		for _, v := range res {
+			st.reject(v.MatchStr, reasonTooManyGenera)
+		}
+		return nil, nil
+	}
+	return m.filterDataSources(res, st), nil
+}
+
+// inferredEditDistance returns the edit distance between the input with
+// an abbreviated genus and the match string, where the abbreviation is
+// replaced by the genus of the match string. It shows how well the
+// epithets match, while EditDistance of the item includes restored letters
+// of the genus.
+func inferredEditDistance(input, matchStr string) int {
+	_, tail, _ := strings.Cut(input, " ")
+	genus, _, _ := strings.Cut(matchStr, " ")
+	res, _, _ := editdist.ComputeDistance(genus+" "+tail, matchStr, false)
+	return res
+}
+
+// genusInferred checks if the input of a match item has an abbreviated
+// genus, so the genus of the item was inferred.
+func genusInferred(input string) bool {
+	genus, _, _ := strings.Cut(input, " ")
+	return strings.HasSuffix(genus, ".")
+}
+
+// extendedMatchType returns a match type that is specific to gnmatcher
+// for the ranked items of a match. It returns an empty string if the match
+// type of gnlib describes the match well.
+func extendedMatchType(details []output.ItemDetails) string {
+	switch {
+	case len(details) == 0:
+		return ""
+	case details[0].GenusInferred:
+		return output.AbbreviatedGenus
+	case details[0].VirusMatch != "":
+		return output.ApproximateVirus
+	case details[0].GenusChange == output.GenusRecombined:
+		return output.Recombination
+	}
+	return ""
+}
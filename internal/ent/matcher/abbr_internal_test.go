@@ -0,0 +1,67 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchGenera(t *testing.T) {
+	assert := assert.New(t)
+	b := newBatch([]string{
+		"Pomatomus saltatrix", "P. saltator", "Pardosa moesta L.", "Pardosa",
+		"  Parus major", "not a name", "BOLD:AAA1234", "Bubo bubo",
+	})
+	assert.Equal([]string{"Bubo", "Pardosa", "Parus", "Pomatomus"}, b.genera)
+	assert.Equal([]string{"Pardosa", "Parus"}, b.generaByPrefix("Pa"))
+	assert.Equal([]string{"Pardosa", "Parus", "Pomatomus"}, b.generaByPrefix("P"))
+	assert.Nil(b.generaByPrefix("Q"))
+
+	var nilBatch *batch
+	assert.Nil(nilBatch.generaByPrefix("P"))
+}
+
+// TestAbbreviatedGenus checks resolution of abbreviated genera by other
+// names of the request and by the epithet index.
+func TestAbbreviatedGenus(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, memNames(
+		"Pomatomus saltatrix",
+		"Pomatomus saltator",
+		"Quercus alba",
+	), nil)
+
+	names := []string{"Pomatomus saltator", "P. saltatrix", "Q. alba", "Z. alba"}
+	res, err := m.MatchNamesDetailed(context.Background(), names,
+		config.OptWithExplain(true))
+	assert.Nil(err)
+
+	match := res.Matches[1]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal(output.AbbreviatedGenus, match.ExtendedMatchType)
+	assert.Equal("Pomatomus saltatrix", match.MatchItems[0].MatchStr)
+	assert.Equal("P. saltatrix", match.MatchItems[0].InputStr)
+	assert.Equal(8, match.MatchItems[0].EditDistance)
+	assert.True(match.ItemsDetails[0].GenusInferred)
+	assert.Equal("", match.ItemsDetails[0].GenusChange)
+	assert.Equal(2, len(match.Explanation.Stages))
+
+	match = res.Matches[2]
+	assert.Equal(output.AbbreviatedGenus, match.ExtendedMatchType)
+	assert.Equal("Quercus alba", match.MatchItems[0].MatchStr)
+	assert.Equal("abbreviationEpithet", match.Explanation.Stages[2].Name)
+
+	assert.Equal(vlib.NoMatch, res.Matches[3].MatchType)
+	assert.Equal("", res.Matches[3].ExtendedMatchType)
+	assert.Equal("", res.Matches[0].ExtendedMatchType)
+
+	// gnlib output keeps the edit distance of the restored genus
+	out := m.MatchNames(names)
+	assert.Equal(vlib.Fuzzy, out.Matches[1].MatchType)
+	assert.Equal("P. saltatrix", out.Matches[1].MatchItems[0].InputStr)
+	assert.Equal(8, out.Matches[1].MatchItems[0].EditDistance)
+}
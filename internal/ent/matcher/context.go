@@ -0,0 +1,71 @@
+package matcher
+
+import (
+	"math"
+	"strings"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/output"
+)
+
+// contextBoost is added to the score of a fuzzy or partial match item,
+// if its genus is found in exact matches of the same batch.
+const contextBoost = 0.1
+
+// applyBatchContext is used in batch-context mode after all names of a
+// batch are matched. Names of a checklist usually share genera, so fuzzy
+// and partial match items with a genus from exact matches of the batch get
+// higher scores, and are marked by ContextBoost. Then the items are sorted
+// again, and only the items allowed by BestItemsNum and MinScore settings
+// are kept.
+func (m matcher) applyBatchContext(ms []output.Match) {
+	genera := exactGenera(ms)
+	for i := range ms {
+		match := &ms[i]
+		// items of hybrid formulas are ranked for each parent separately
+		if match.ExtendedMatchType == output.HybridFormula {
+			continue
+		}
+		if len(genera) > 0 && isContextType(match.MatchType) {
+			for j, v := range match.MatchItems {
+				genus, _, _ := strings.Cut(v.MatchStr, " ")
+				if _, ok := genera[genus]; !ok {
+					continue
+				}
+				score := min(match.ItemsDetails[j].Score+contextBoost, 1)
+				match.ItemsDetails[j].Score = math.Round(score*1000) / 1000
+				match.ItemsDetails[j].ContextBoost = true
+			}
+			sortItems(&match.Match, match.ItemsDetails)
+		}
+		match.ItemsDetails = m.selectItems(&match.Match, match.ItemsDetails)
+		match.ExtendedMatchType = extendedMatchType(match.ItemsDetails)
+	}
+}
+
+// exactGenera collects genera of exact matches.
+func exactGenera(ms []output.Match) map[string]struct{} {
+	res := make(map[string]struct{})
+	for i := range ms {
+		mt := ms[i].MatchType
+		if mt != vlib.Exact && mt != vlib.ExactSpeciesGroup {
+			continue
+		}
+		for _, v := range ms[i].MatchItems {
+			genus, _, _ := strings.Cut(v.MatchStr, " ")
+			res[genus] = struct{}{}
+		}
+	}
+	return res
+}
+
+// isContextType checks if scores of a match with the match type can be
+// changed by the batch context.
+func isContextType(mt vlib.MatchTypeValue) bool {
+	switch mt {
+	case vlib.Fuzzy, vlib.FuzzyRelaxed,
+		vlib.FuzzySpeciesGroup, vlib.FuzzySpeciesGroupRelaxed:
+		return true
+	}
+	return isPartial(mt)
+}
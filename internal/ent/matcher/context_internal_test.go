@@ -0,0 +1,40 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/io/memio"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBatchContext checks that genera of exact matches of a request boost
+// scores of fuzzy items of other names.
+func TestBatchContext(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, []memio.Name{
+		{Name: "Pardosa moesta", DataSources: []int{1}},
+		{Name: "Pardasa moesta", DataSources: []int{1, 2, 3}},
+		{Name: "Pardosa lugubris", DataSources: []int{1}},
+	}, nil)
+
+	names := []string{"Pardesa moesta", "Pardosa lugubris"}
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+	match := res.Matches[0]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal(2, len(match.MatchItems))
+	assert.Equal("Pardasa moesta", match.MatchItems[0].MatchStr)
+	assert.False(match.ItemsDetails[1].ContextBoost)
+
+	res, err = m.MatchNamesDetailed(context.Background(), names,
+		config.OptWithBatchContext(true), config.OptBestItemsNum(1))
+	assert.Nil(err)
+	match = res.Matches[0]
+	assert.Equal(1, len(match.MatchItems))
+	assert.Equal("Pardosa moesta", match.MatchItems[0].MatchStr)
+	assert.True(match.ItemsDetails[0].ContextBoost)
+	assert.False(res.Matches[1].ItemsDetails[0].ContextBoost)
+}
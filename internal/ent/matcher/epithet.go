@@ -0,0 +1,146 @@
+package matcher
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/gnames/gnparser/ent/stemmer"
+	"github.com/gnames/levenshtein/ent/editdist"
+)
+
+const (
+	// maxGenusEditDist is the maximal edit distance between the genus of
+	// the input and the genus of a canonical form found by the epithet,
+	// when the genus is considered misspelled.
+	maxGenusEditDist = 2
+
+	// maxRecombinations is the maximal number of genera with the same
+	// epithet that are returned as recombinations. If an epithet is found
+	// in more genera, it does not tell much about the name.
+	maxRecombinations = 5
+)
+
+const (
+	// reasonTooManyGenera is the reason of rejection of recombinations with
+	// a common epithet.
+	reasonTooManyGenera = "epithet is found in too many genera"
+
+	// reasonNoRecombinations is the reason of rejection of recombinations
+	// when they are not requested.
+	reasonNoRecombinations = "recombinations are not requested"
+)
+
+// matchEpithet finds canonical forms that have the same stemmed epithets
+// as the input, but a different genus. It finds names with a genus that is
+// misspelled beyond the limits of fuzzy matching, like 'Pomatomos
+// saltatrix', and, if WithRecombinations is set, names that were moved to
+// another genus. Recombinations are returned only if there are no canonical
+// forms with a similar genus, and if the epithet is found in a few genera.
+func (m matcher) matchEpithet(ns nameString) (res *mlib.Match, err error) {
+	st := m.trace.begin(stageEpithet, ns.CanonicalStem)
+	defer func() { st.end(res) }()
+
+	epithet := fuzzy.SpecificEpithet(ns.CanonicalStem)
+	if epithet == "" {
+		return nil, nil
+	}
+	items, err := m.fuzzyMatcher.EpithetToMatchItems(epithet)
+	if err != nil {
+		return nil, err
+	}
+
+	matchType := vlib.Fuzzy
+	if m.cfg.WithRelaxedFuzzyMatch {
+		matchType = vlib.FuzzyRelaxed
+	}
+
+	words := strings.Split(ns.CanonicalStem, " ")
+	var similar, recombined []mlib.MatchItem
+	genera := make(map[string]struct{})
+	for _, v := range items {
+		stem := stemmer.StemCanonical(v.MatchStr)
+		stemWords := strings.Split(stem, " ")
+		if stemWords[0] == words[0] ||
+			!slices.Equal(stemWords[1:], words[1:]) {
+			continue
+		}
+		st.candidates([]string{stem})
+
+		v.InputStr = ns.Canonical
+		v.EditDistance, _, _ = editdist.ComputeDistance(
+			ns.Canonical, v.MatchStr, false,
+		)
+		v.EditDistanceStem, _, _ = editdist.ComputeDistance(
+			ns.CanonicalStem, stem, false,
+		)
+		v.MatchType = matchType
+		if isSimilarGenus(words[0], stemWords[0]) {
+			similar = append(similar, v)
+			continue
+		}
+		if !m.cfg.WithRecombinations {
+			st.reject(v.MatchStr, reasonNoRecombinations)
+			continue
+		}
+		genera[stemWords[0]] = struct{}{}
+		recombined = append(recombined, v)
+	}
+
+	matchItems := m.filterDataSources(similar, st)
+	if len(matchItems) == 0 && len(recombined) > 0 {
+		if len(genera) > maxRecombinations {
+			for _, v := range recombined {
+				st.reject(v.MatchStr, reasonTooManyGenera)
+			}
+		} else {
+			matchItems = m.filterDataSources(recombined, st)
+		}
+	}
+	if len(matchItems) == 0 {
+		return nil, nil
+	}
+
+	res = &mlib.Match{
+		ID:         ns.ID,
+		Name:       ns.Name,
+		MatchType:  matchType,
+		MatchItems: matchItems,
+	}
+	return res, nil
+}
+
+// isSimilarGenus checks if two genera differ only by a few edits. Rules of
+// fuzzy matching for short words are not used, because the genera are
+// compared only if the epithets are the same. Edits have to change less
+// than a half of the shorter genus.
+func isSimilarGenus(genus1, genus2 string) bool {
+	size := min(utf8.RuneCountInString(genus1), utf8.RuneCountInString(genus2))
+	maxED := min(maxGenusEditDist, (size-1)/2)
+	ed, exceeded := editdist.ComputeDistanceMax(genus1, genus2, maxED)
+	return !exceeded && ed <= maxED
+}
+
+// genusChange tells if a match item has a different genus than the input,
+// while other words of their stems are the same. The genus is misspelled,
+// if the genera are similar, otherwise the name was recombined.
+func genusChange(input, matchStr string) string {
+	genus1, _, _ := strings.Cut(input, " ")
+	genus2, _, _ := strings.Cut(matchStr, " ")
+	if genus1 == genus2 || genusInferred(input) {
+		return ""
+	}
+	words1 := strings.Split(stemmer.StemCanonical(input), " ")
+	words2 := strings.Split(stemmer.StemCanonical(matchStr), " ")
+	if len(words1) < 2 || !slices.Equal(words1[1:], words2[1:]) {
+		return ""
+	}
+	if isSimilarGenus(genus1, genus2) {
+		return output.GenusMisspelled
+	}
+	return output.GenusRecombined
+}
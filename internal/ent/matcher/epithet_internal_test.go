@@ -0,0 +1,113 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenusChange(t *testing.T) {
+	testData := []struct {
+		input, matchStr, res string
+	}{
+		{"Aus bus", "Aus bus", ""},
+		{"Aus bus", "Aus bas", ""},
+		{"Aus", "Cus", ""},
+		{"Pomatomos saltatrix", "Pomatomus saltatrix", output.GenusMisspelled},
+		{"Lycosa moestus", "Pardosa moesta", output.GenusRecombined},
+		{"Lycosa moesta", "Pardosa lugubris", ""},
+		{"Aux bus", "Aus bus", output.GenusMisspelled},
+	}
+	for _, v := range testData {
+		assert.Equal(t, v.res, genusChange(v.input, v.matchStr), v.input)
+	}
+}
+
+// TestMatchEpithet checks matching of names with a misspelled or changed
+// genus by their epithets.
+func TestMatchEpithet(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, memNames("Pomatomus saltatrix", "Pardosa moesta"), nil)
+
+	names := []string{"Pomatemas saltatrix", "Lycosa moestus", "Lycosa lugubris"}
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+
+	match := res.Matches[0]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal("Pomatomus saltatrix", match.MatchItems[0].MatchStr)
+	assert.Equal(2, match.MatchItems[0].EditDistance)
+	assert.Equal(output.GenusMisspelled, match.ItemsDetails[0].GenusChange)
+
+	assert.Equal("", match.ExtendedMatchType)
+
+	// recombinations are not returned by default
+	assert.Equal(vlib.NoMatch, res.Matches[1].MatchType)
+	assert.Equal(vlib.NoMatch, res.Matches[2].MatchType)
+
+	res, err = m.MatchNamesDetailed(
+		context.Background(), names, config.OptWithRecombinations(true),
+	)
+	assert.Nil(err)
+
+	match = res.Matches[1]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal(output.Recombination, match.ExtendedMatchType)
+	assert.Equal("Pardosa moesta", match.MatchItems[0].MatchStr)
+	assert.Equal(output.GenusRecombined, match.ItemsDetails[0].GenusChange)
+
+	assert.Equal(vlib.NoMatch, res.Matches[2].MatchType)
+}
+
+// TestMatchEpithetKnownGenus checks that the epithet stage is preferred to
+// a partial match that found only the genus of the input.
+func TestMatchEpithetKnownGenus(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, memNames(
+		"Homo", "Aus", "Cus", "Aus bus", "Cus bus", "Aus buscus",
+	), nil)
+
+	names := []string{"Homo bus", "Cus buscus", "Aux bus"}
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+
+	// recombinations are not requested, the genus is kept
+	match := res.Matches[0]
+	assert.Equal(vlib.PartialExact, match.MatchType)
+	assert.Equal("Homo", match.MatchItems[0].MatchStr)
+
+	// a short misspelled genus is not a recombination
+	match = res.Matches[1]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal("Aus buscus", match.MatchItems[0].MatchStr)
+	assert.Equal(output.GenusMisspelled, match.ItemsDetails[0].GenusChange)
+	assert.Equal("", match.ExtendedMatchType)
+
+	match = res.Matches[2]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal(1, len(match.MatchItems))
+	assert.Equal("Aus bus", match.MatchItems[0].MatchStr)
+	assert.Equal(output.GenusMisspelled, match.ItemsDetails[0].GenusChange)
+
+	res, err = m.MatchNamesDetailed(
+		context.Background(), names, config.OptWithRecombinations(true),
+	)
+	assert.Nil(err)
+
+	match = res.Matches[0]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal(output.Recombination, match.ExtendedMatchType)
+	var matchStrs []string
+	for _, v := range match.MatchItems {
+		matchStrs = append(matchStrs, v.MatchStr)
+	}
+	assert.ElementsMatch([]string{"Aus bus", "Cus bus"}, matchStrs)
+
+	match = res.Matches[1]
+	assert.Equal("Aus buscus", match.MatchItems[0].MatchStr)
+	assert.Equal("", match.ExtendedMatchType)
+}
@@ -3,10 +3,18 @@ package matcher
 import (
 	mlib "github.com/gnames/gnlib/ent/matcher"
 	vlib "github.com/gnames/gnlib/ent/verifier"
-	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
 )
 
-func (m matcher) matchStem(ns nameString) (*mlib.Match, error) {
+// matchStem finds canonical forms that have the same stem as the
+// name-string. The stage is the name of the matching stage for explain
+// mode.
+func (m matcher) matchStem(
+	ns nameString,
+	stage string,
+) (res *mlib.Match, err error) {
+	st := m.trace.begin(stage, ns.CanonicalStem)
+	defer func() { st.end(res) }()
+
 	matches, err := m.exactStemMatches(ns.CanonicalStemID, ns.CanonicalStem)
 	if err != nil {
 		return nil, err
@@ -17,16 +25,16 @@ func (m matcher) matchStem(ns nameString) (*mlib.Match, error) {
 	}
 	matchType := vlib.Fuzzy
 	matchItems := make([]mlib.MatchItem, 0, len(matches))
-	relax := m.cfg.WithRelaxedFuzzyMatch
 	for _, v := range matches {
 		v.InputStr = ns.Canonical
 		if v.MatchStr == v.InputStr {
 			v.MatchType = vlib.Exact
 			matchType = vlib.Exact
 		} else {
-			editDistance := fuzzy.EditDistance(v.InputStr, v.MatchStr, relax)
+			editDistance, reason := m.editDistance(v.InputStr, v.MatchStr)
 			// editDistance went over threshold
 			if editDistance == -1 {
+				st.reject(v.MatchStr, reason)
 				continue
 			}
 			v.EditDistance = editDistance
@@ -35,12 +43,12 @@ func (m matcher) matchStem(ns nameString) (*mlib.Match, error) {
 		matchItems = append(matchItems, v)
 	}
 
-	matchItems = m.filterDataSources(matchItems)
+	matchItems = m.filterDataSources(matchItems, st)
 	if len(matchItems) == 0 {
 		return nil, nil
 	}
 
-	res := &mlib.Match{
+	res = &mlib.Match{
 		ID:         ns.ID,
 		Name:       ns.Name,
 		MatchType:  matchType,
@@ -50,11 +58,20 @@ func (m matcher) matchStem(ns nameString) (*mlib.Match, error) {
 }
 
 // matchVirus returns the "virus" name the way it was given, without matching.
-func (m matcher) matchVirus(ns nameString) (*mlib.Match, error) {
+func (m matcher) matchVirus(ns nameString) (res *mlib.Match, err error) {
+	st := m.trace.begin(stageVirus, ns.Name)
+	defer func() { st.end(res) }()
+
 	matchItems, err := m.virusMatcher.MatchVirus(ns.Name)
 	if err != nil {
 		return nil, err
 	}
+	if len(matchItems) == 0 {
+		matchItems, err = m.matchVirusFuzzy(ns)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	matchType := vlib.Virus
 	if len(matchItems) == 0 {
@@ -63,7 +80,7 @@ func (m matcher) matchVirus(ns nameString) (*mlib.Match, error) {
 	for i := range matchItems {
 		matchItems[i].InputStr = ns.Name
 	}
-	res := &mlib.Match{
+	res = &mlib.Match{
 		ID:         ns.ID,
 		Name:       ns.Name,
 		MatchType:  matchType,
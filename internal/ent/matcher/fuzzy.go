@@ -12,7 +12,10 @@ func (m matcher) matchFuzzy(
 	canonical,
 	stem string,
 	ns nameString,
-) (*mlib.Match, error) {
+) (res *mlib.Match, err error) {
+	st := m.trace.begin(stageFuzzy, stem)
+	defer func() { st.end(res) }()
+
 	relax := m.cfg.WithRelaxedFuzzyMatch
 
 	matchType := vlib.Fuzzy
@@ -20,12 +23,15 @@ func (m matcher) matchFuzzy(
 		matchType = vlib.FuzzyRelaxed
 	}
 
-	stemMatches := m.fuzzyMatcher.MatchStem(stem)
+	maxEditDist := m.maxEditDist(stem)
+	checkMax := m.ocr != nil || m.cfg.WithAdaptiveEditDist
+	stemMatches := m.stemCandidates(stem)
+	st.candidates(stemMatches)
 	if len(stemMatches) == 0 {
 		return nil, nil
 	}
 
-	res := &mlib.Match{
+	res = &mlib.Match{
 		ID:         ns.ID,
 		Name:       ns.Name,
 		MatchType:  matchType,
@@ -33,9 +39,17 @@ func (m matcher) matchFuzzy(
 	}
 
 	for _, stemMatch := range stemMatches {
-		editDistanceStem := fuzzy.EditDistance(stemMatch, stem, relax)
+		editDistanceStem, reason := m.editDistance(stemMatch, stem)
 		// -1 means edit distance got over threshold
 		if editDistanceStem == -1 {
+			st.reject(stemMatch, reason)
+			continue
+		}
+		// candidates found by OCR variants can be too far from the stem,
+		// and adaptive edit distance can be stricter than the checks of
+		// edit distance.
+		if checkMax && editDistanceStem > maxEditDist {
+			st.reject(stemMatch, fuzzy.ReasonOverMaxEditDist)
 			continue
 		}
 		matchItems, err := m.fuzzyMatcher.StemToMatchItems(stemMatch)
@@ -46,13 +60,13 @@ func (m matcher) matchFuzzy(
 		for _, matchItem := range matchItems {
 			matchItem.InputStr = canonical
 			// runs edit distance with checks, returns -1 if checks failed.
-			editDistance := fuzzy.EditDistance(
+			editDistance, reason := m.editDistance(
 				matchItem.InputStr,
 				matchItem.MatchStr,
-				relax,
 			)
 			// skip matches that failed edit distance checks.
 			if editDistance == -1 {
+				st.reject(matchItem.MatchStr, reason)
 				continue
 			}
 
@@ -63,10 +77,37 @@ func (m matcher) matchFuzzy(
 		}
 	}
 
-	res.MatchItems = m.filterDataSources(res.MatchItems)
+	res.MatchItems = m.filterDataSources(res.MatchItems, st)
 	if len(res.MatchItems) == 0 {
 		return nil, nil
 	}
 
 	return res, nil
 }
+
+// stemCandidates returns stems from the trie that are close to the given
+// stem. If OCR distance is requested, it also searches for variants of the
+// stem with fixed OCR confusions, because such candidates are often too far
+// from the stem by Levenshtein distance.
+func (m matcher) stemCandidates(stem string) []string {
+	opts := m.fuzzyOpts(stem)
+	res := m.fuzzyMatcher.MatchStem(stem, opts)
+	if m.ocr == nil {
+		return res
+	}
+
+	seen := make(map[string]struct{}, len(res))
+	for _, v := range res {
+		seen[v] = struct{}{}
+	}
+	for _, variant := range m.ocr.Variants(stem) {
+		for _, v := range m.fuzzyMatcher.MatchStem(variant, opts) {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+			res = append(res, v)
+		}
+	}
+	return res
+}
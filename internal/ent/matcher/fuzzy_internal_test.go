@@ -4,6 +4,10 @@ import (
 	"testing"
 
 	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/io/memio"
 	"github.com/gnames/gnmatcher/pkg/config"
 	"github.com/stretchr/testify/assert"
 )
@@ -38,15 +42,17 @@ type fuzzyMatcherMock struct{}
 
 func (fuzzyMatcherMock) Init() error { return nil }
 
-func (fuzzyMatcherMock) SetConfig(cfg config.Config) {}
-
-func (fuzzyMatcherMock) MatchStem(stem string) []string {
+func (fuzzyMatcherMock) MatchStem(stem string, opts fuzzy.Options) []string {
 	if stems, ok := matchStemMock[stem]; ok {
 		return stems
 	}
 	return []string{}
 }
 
+func (fuzzyMatcherMock) AddStems(data provider.DataProvider) (int, error) {
+	return 0, nil
+}
+
 func (fuzzyMatcherMock) MatchStemExact(stem string) bool {
 	return true
 }
@@ -60,3 +66,82 @@ func (fuzzyMatcherMock) StemToMatchItems(
 	}
 	return res, nil
 }
+
+func (fuzzyMatcherMock) EpithetToMatchItems(
+	epithet string,
+) ([]mlib.MatchItem, error) {
+	return nil, nil
+}
+
+func (fuzzyMatcherMock) SpeciesToMatchItems(
+	species string,
+) ([]mlib.MatchItem, error) {
+	return nil, nil
+}
+
+// TestAdaptiveEditDist checks that with adaptive edit distance only long
+// names get edit distance 2.
+func TestAdaptiveEditDist(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		msg, stem string
+		adaptive  bool
+		res       int
+	}{
+		{"not adaptive", "Pardosa moest", false, 2},
+		{"short", "Pardosa moest", true, 1},
+		{"long binomial", "Pseudoscorpiones brevicornut", true, 2},
+		{"trinomial", "Pardosa moest moest major", true, 2},
+		{"short trinomial", "Aus bus cus", true, 1},
+	}
+	for _, v := range tests {
+		m := matcher{cfg: config.New(
+			config.OptMaxEditDist(2),
+			config.OptWithAdaptiveEditDist(v.adaptive),
+		)}
+		assert.Equal(v.res, m.maxEditDist(v.stem), v.msg)
+		assert.Equal(v.res, m.fuzzyOpts(v.stem).MaxEditDist, v.msg)
+	}
+
+	m := matcher{
+		fuzzyMatcher: fuzzyMatcherMock{},
+		cfg:          config.New(config.OptWithAdaptiveEditDist(true)),
+	}
+	ns := nameString{ID: "123", Name: "Pardosa maesta"}
+	res, err := m.matchFuzzy("Pardosa maesta", "Pardosa maest", ns)
+	assert.Nil(err)
+	assert.Equal(1, len(res.MatchItems))
+
+	m.cfg = config.New(
+		config.OptWithAdaptiveEditDist(true),
+		config.OptEditDistThresholds([]config.EditDistThreshold{
+			{MinLength: 20, MaxEditDist: 1},
+		}),
+	)
+	res, err = m.matchFuzzy("Pardosa maesta", "Pardosa maest", ns)
+	assert.Nil(err)
+	assert.Nil(res)
+}
+
+// TestOCRDistance checks that OCR errors are matched only if the request
+// asks for OCR-aware edit distance.
+func TestOCRDistance(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, []memio.Name{
+		{Name: "Pardosa moesta", DataSources: []int{3}},
+		{Name: "Pardosa dentata", DataSources: []int{1}},
+	}, nil)
+
+	names := []string{"Pardosa rnoesta", "Pardosa clentata"}
+	res := m.MatchNames(names)
+	assert.Equal(vlib.NoMatch, res.Matches[0].MatchType)
+	assert.Equal(vlib.NoMatch, res.Matches[1].MatchType)
+
+	res = m.MatchNames(names, config.OptWithOCRDistance(true))
+	assert.Equal(vlib.Fuzzy, res.Matches[0].MatchType)
+	item := res.Matches[0].MatchItems[0]
+	assert.Equal("Pardosa moesta", item.MatchStr)
+	assert.Equal(1, item.EditDistance)
+	assert.Equal(vlib.Fuzzy, res.Matches[1].MatchType)
+	assert.Equal("Pardosa dentata", res.Matches[1].MatchItems[0].MatchStr)
+}
@@ -0,0 +1,136 @@
+package matcher
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/gnames/gnmatcher/internal/ent/exact"
+	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
+	"github.com/gnames/gnmatcher/internal/ent/virus"
+)
+
+// generation is a set of matchers that use the same version of lookup
+// data. Matching requests keep the generation they started with, so a new
+// generation can replace the current one without affecting them.
+type generation struct {
+	exactMatcher exact.ExactMatcher
+	fuzzyMatcher fuzzy.FuzzyMatcher
+	virusMatcher virus.VirusMatcher
+
+	// pool contains workers that match names of requests that use the
+	// generation.
+	pool *pool
+
+	// inUse counts requests that still work with the generation.
+	inUse sync.WaitGroup
+}
+
+// generations keeps the current generation of lookup data. It is shared
+// by all copies of the matcher.
+type generations struct {
+	mu      sync.RWMutex
+	current *generation
+}
+
+func newGenerations(
+	em exact.ExactMatcher,
+	fm fuzzy.FuzzyMatcher,
+	vm virus.VirusMatcher,
+	p *pool,
+) *generations {
+	gen := &generation{
+		exactMatcher: em,
+		fuzzyMatcher: fm,
+		virusMatcher: vm,
+		pool:         p,
+	}
+	return &generations{current: gen}
+}
+
+// acquire returns a copy of the matcher that uses the current generation
+// of lookup data, and a function that has to be called when the copy is
+// not needed anymore.
+func (m matcher) acquire() (matcher, func()) {
+	if m.gens == nil {
+		return m, func() {}
+	}
+	m.gens.mu.RLock()
+	gen := m.gens.current
+	gen.inUse.Add(1)
+	m.gens.mu.RUnlock()
+
+	m.exactMatcher = gen.exactMatcher
+	m.fuzzyMatcher = gen.fuzzyMatcher
+	m.virusMatcher = gen.virusMatcher
+	m.pool = gen.pool
+	return m, gen.inUse.Done
+}
+
+func (m matcher) Reload(
+	em exact.ExactMatcher,
+	fm fuzzy.FuzzyMatcher,
+	vm virus.VirusMatcher,
+) (<-chan struct{}, error) {
+	if m.gens == nil {
+		return nil, errors.New("matcher was not created by NewMatcher")
+	}
+
+	next := matcher{exactMatcher: em, fuzzyMatcher: fm, virusMatcher: vm}
+	if err := next.Init(); err != nil {
+		return nil, err
+	}
+	gen := &generation{
+		exactMatcher: em,
+		fuzzyMatcher: fm,
+		virusMatcher: vm,
+		pool:         newPool(m.cfg.JobsNum, m.cfg.QueueSize),
+	}
+
+	m.gens.mu.Lock()
+	old := m.gens.current
+	m.gens.current = gen
+	m.gens.mu.Unlock()
+
+	released := make(chan struct{})
+	go func() {
+		old.inUse.Wait()
+		old.close()
+		close(released)
+	}()
+	return released, nil
+}
+
+func (m matcher) Close() error {
+	if m.gens == nil {
+		if m.pool != nil {
+			m.pool.close()
+		}
+		return nil
+	}
+	m.gens.mu.RLock()
+	gen := m.gens.current
+	m.gens.mu.RUnlock()
+	gen.inUse.Wait()
+	gen.close()
+	return nil
+}
+
+// close stops workers of the generation, and releases resources held by
+// its matchers, if they have any.
+func (g *generation) close() {
+	if g.pool != nil {
+		g.pool.close()
+	}
+	ms := []any{g.exactMatcher, g.fuzzyMatcher, g.virusMatcher}
+	for _, v := range ms {
+		c, ok := v.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := c.Close(); err != nil {
+			slog.Warn("Cannot close previous lookup data", "error", err)
+		}
+	}
+}
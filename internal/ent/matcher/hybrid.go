@@ -0,0 +1,84 @@
+package matcher
+
+import (
+	"strings"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/gnames/gnparser"
+	"github.com/gnames/gnparser/ent/parsed"
+)
+
+// hybridSign separates parents in canonical forms of hybrid formulas.
+const hybridSign = " × "
+
+// isHybridFormula checks if a parsed name is a hybrid formula, like
+// 'Salix alba × Salix fragilis'. The parser restores abbreviated genera
+// of parents, so 'Mentha aquatica x M. spicata' has the canonical form
+// 'Mentha aquatica × Mentha spicata'.
+func isHybridFormula(prsd *parsed.Parsed) bool {
+	return prsd.Parsed && prsd.Hybrid != nil &&
+		*prsd.Hybrid == parsed.HybridFormulaAnnot
+}
+
+// matchHybrid matches every parent of a hybrid formula separately by all
+// matching stages, and combines the results. Items of each parent are
+// ranked separately, so BestItemsNum and MinScore settings apply to each
+// parent. Details of the items contain the number of the parent, starting
+// from 1.
+//
+// The formula is not found as a whole, so the combined match type is
+// PartialExact, if all matched parents are exact matches, PartialFuzzy
+// otherwise, or NoMatch if none of the parents matched.
+func (m matcher) matchHybrid(
+	parser gnparser.GNparser,
+	ns nameString,
+) (res mlib.Match, details []output.ItemDetails, err error) {
+	st := m.trace.begin(stageHybrid, ns.Canonical)
+	defer func() { st.end(&res) }()
+
+	var items []mlib.MatchItem
+	exact := true
+	for i, parent := range strings.Split(ns.Canonical, hybridSign) {
+		pns, pprsd := newNameString(parser, parent, m.cfg.NomCode)
+		if !pprsd.Parsed || isHybridFormula(pprsd) {
+			continue
+		}
+		match, err := m.matchStages(parser, pns, pprsd)
+		if err != nil {
+			return mlib.Match{}, nil, err
+		}
+		parentDetails := m.selectItems(&match, scoreItems(pns, &match))
+		if len(parentDetails) == 0 {
+			continue
+		}
+		if match.MatchType != vlib.Exact &&
+			match.MatchType != vlib.ExactSpeciesGroup {
+			exact = false
+		}
+		for j := range parentDetails {
+			parentDetails[j].HybridParent = i + 1
+		}
+		items = append(items, match.MatchItems...)
+		details = append(details, parentDetails...)
+	}
+
+	res = mlib.Match{
+		ID:         ns.ID,
+		Name:       ns.Name,
+		MatchType:  vlib.NoMatch,
+		MatchItems: items,
+	}
+	switch {
+	case len(items) == 0:
+		return res, nil, nil
+	case exact:
+		res.MatchType = vlib.PartialExact
+	case m.cfg.WithRelaxedFuzzyMatch:
+		res.MatchType = vlib.PartialFuzzyRelaxed
+	default:
+		res.MatchType = vlib.PartialFuzzy
+	}
+	return res, details, nil
+}
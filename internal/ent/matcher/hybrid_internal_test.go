@@ -0,0 +1,60 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHybridFormula checks that parents of hybrid formulas are matched
+// separately, if the formula is not found as a whole.
+func TestHybridFormula(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, memNames(
+		"Salix alba",
+		"Salix fragilis",
+		"Mentha aquatica",
+		"Mentha spicata",
+		"Quercus alba × Quercus robur",
+	), nil)
+
+	names := []string{
+		"Salix alba × Salix fragilis",
+		"Mentha aquatica x M. spicatta",
+		"Salix alba × Populus nigra",
+		"Quercus alba × Quercus robur",
+		"Populus tremula × Populus nigra",
+	}
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+
+	match := res.Matches[0]
+	assert.Equal(vlib.PartialExact, match.MatchType)
+	assert.Equal(output.HybridFormula, match.ExtendedMatchType)
+	assert.Equal(2, len(match.MatchItems))
+	assert.Equal("Salix alba", match.MatchItems[0].MatchStr)
+	assert.Equal(vlib.Exact, match.MatchItems[0].MatchType)
+	assert.Equal(1, match.ItemsDetails[0].HybridParent)
+	assert.Equal("Salix fragilis", match.MatchItems[1].MatchStr)
+	assert.Equal(2, match.ItemsDetails[1].HybridParent)
+
+	match = res.Matches[1]
+	assert.Equal(vlib.PartialFuzzy, match.MatchType)
+	assert.Equal(2, len(match.MatchItems))
+	assert.Equal("Mentha spicata", match.MatchItems[1].MatchStr)
+	assert.Equal(vlib.Fuzzy, match.MatchItems[1].MatchType)
+
+	match = res.Matches[2]
+	assert.Equal(vlib.PartialExact, match.MatchType)
+	assert.Equal(1, len(match.MatchItems))
+	assert.Equal(1, match.ItemsDetails[0].HybridParent)
+
+	match = res.Matches[3]
+	assert.Equal(vlib.Exact, match.MatchType)
+	assert.Equal("", match.ExtendedMatchType)
+
+	assert.Equal(vlib.NoMatch, res.Matches[4].MatchType)
+}
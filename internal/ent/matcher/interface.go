@@ -3,8 +3,15 @@
 package matcher
 
 import (
+	"context"
+
 	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/ent/exact"
+	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/ent/virus"
 	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
 )
 
 // Matcher is the interface that enables matching strings to known scientific
@@ -15,5 +22,57 @@ type Matcher interface {
 	Init() error
 	// MatchNames takes a slice of strings and returns back metadata
 	// of the request and the matches of the strings to known scientific names.
+	// Extended match types are returned only by MatchNamesDetailed.
 	MatchNames(names []string, opt ...config.Option) mlib.Output
+
+	// MatchNamesCtx is the same as MatchNames, but it stops matching when
+	// the context is canceled or its deadline is exceeded. In such a case
+	// it returns names matched so far, the rest of the names get NoMatch
+	// result, and the context error is returned.
+	MatchNamesCtx(
+		ctx context.Context,
+		names []string,
+		opt ...config.Option,
+	) (mlib.Output, error)
+
+	// MatchNamesDetailed is the same as MatchNamesCtx, but returns detailed
+	// output, where every match contains an error, if matching of the
+	// name-string failed. The returned error is not nil if the context was
+	// canceled, or if matching of any name-string failed.
+	MatchNamesDetailed(
+		ctx context.Context,
+		names []string,
+		opt ...config.Option,
+	) (output.Output, error)
+
+	// MatchStream takes names from the input channel and sends matches
+	// to the returned channel in the same order. There is no limit on the
+	// number of names. The output channel is closed when the input channel
+	// is closed and all names are processed, or when the context is
+	// canceled. If matching of a name fails, its result contains the error.
+	MatchStream(
+		ctx context.Context,
+		chNames <-chan string,
+		opt ...config.Option,
+	) <-chan output.Match
+
+	// AddStems adds stems, canonical forms and their data-sources from the
+	// data provider to the current lookup data. It returns the number of
+	// stems that were not known before.
+	AddStems(data provider.DataProvider) (int, error)
+
+	// Reload initializes given matchers and makes them current. Requests
+	// that started before Reload continue with the previous matchers. The
+	// returned channel is closed when all such requests are finished, and
+	// resources of the previous matchers and their workers are released.
+	Reload(
+		em exact.ExactMatcher,
+		fm fuzzy.FuzzyMatcher,
+		vm virus.VirusMatcher,
+	) (<-chan struct{}, error)
+
+	// Close waits until running requests are finished, stops workers and
+	// releases resources of the current matchers. The matcher cannot be
+	// used after Close.
+	Close() error
 }
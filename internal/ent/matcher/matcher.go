@@ -2,18 +2,24 @@ package matcher
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"slices"
-	"sync"
+	"strings"
+	"unicode/utf8"
 
 	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnlib/ent/nomcode"
 	vlib "github.com/gnames/gnlib/ent/verifier"
 	"github.com/gnames/gnmatcher/internal/ent/exact"
 	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
 	"github.com/gnames/gnmatcher/internal/ent/virus"
 	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
 	"github.com/gnames/gnparser"
 	"github.com/gnames/gnparser/ent/parsed"
+	"github.com/gnames/gnuuid"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -21,6 +27,11 @@ const (
 	// MaxMaxNamesNum is the largest number of names that can be processed
 	// per request. If input contains more names, it will be truncated.
 	MaxNamesNum = 10_000
+
+	// streamWindow is the maximal number of names that are taken from the
+	// input of MatchStream, but are not yet sent to its output. It limits
+	// memory used for keeping the order of results.
+	streamWindow = 1_000
 )
 
 type matcher struct {
@@ -28,20 +39,44 @@ type matcher struct {
 	fuzzyMatcher fuzzy.FuzzyMatcher
 	virusMatcher virus.VirusMatcher
 	cfg          config.Config
+
+	// gens contains the current generation of matchers, that replaces
+	// the matchers above after Reload.
+	gens *generations
+
+	// pool contains workers that match names of requests. Every generation
+	// of lookup data has its own pool.
+	pool *pool
+
+	// batch contains data about all names of the request. It is nil for
+	// streams of names.
+	batch *batch
+
+	// ocr is the weighted edit distance model of the request. It is nil
+	// if WithOCRDistance is false.
+	ocr *fuzzy.OCR
+
+	// trace records matching of a name-string in explain mode. It is set
+	// only in a copy of the matcher that matches one name-string.
+	trace *trace
 }
 
 // NewMatcher returns Matcher object. It takes interfaces to ExactMatcher
-// and FuzzyMatcher.
+// and FuzzyMatcher. It starts JobsNum workers that are shared by all
+// matching requests. The workers are stopped by Close.
 func NewMatcher(
 	em exact.ExactMatcher,
 	fm fuzzy.FuzzyMatcher,
 	vm virus.VirusMatcher,
 	cfg config.Config) Matcher {
+	p := newPool(cfg.JobsNum, cfg.QueueSize)
 	return matcher{
 		exactMatcher: em,
 		fuzzyMatcher: fm,
 		virusMatcher: vm,
 		cfg:          cfg,
+		gens:         newGenerations(em, fm, vm, p),
+		pool:         p,
 	}
 }
 
@@ -69,78 +104,248 @@ func (m matcher) Init() error {
 	return nil
 }
 
+func (m matcher) AddStems(data provider.DataProvider) (int, error) {
+	m, release := m.acquire()
+	defer release()
+
+	// Stems are added to the bloom filter last, otherwise they could be
+	// found by exact matching before their canonical forms are saved.
+	num, err := m.fuzzyMatcher.AddStems(data)
+	if err != nil {
+		return 0, err
+	}
+	err = m.exactMatcher.AddStems(data)
+	if err != nil {
+		return 0, err
+	}
+	return num, nil
+}
+
 type nameIn struct {
 	index int
 	name  string
 }
 
 type matchOut struct {
-	index int
-	match mlib.Match
+	index       int
+	match       mlib.Match
+	extType     string
+	details     []output.ItemDetails
+	explanation *output.Explanation
+	err         error
 }
 
 func (m matcher) MatchNames(
 	names []string,
 	opts ...config.Option,
 ) mlib.Output {
-	chIn := make(chan nameIn)
-	chOut := make(chan matchOut)
-	var wgIn sync.WaitGroup
-	var wgOut sync.WaitGroup
-	wgIn.Add(m.cfg.JobsNum)
-	wgOut.Add(1)
+	res, _ := m.MatchNamesCtx(context.Background(), names, opts...)
+	return res
+}
 
-	for _, opt := range opts {
-		opt(&m.cfg)
-	}
+func (m matcher) MatchNamesCtx(
+	ctx context.Context,
+	names []string,
+	opts ...config.Option,
+) (mlib.Output, error) {
+	res, err := m.MatchNamesDetailed(ctx, names, opts...)
+	return res.MatcherOutput(), err
+}
 
-	m.exactMatcher.SetConfig(m.cfg)
-	m.fuzzyMatcher.SetConfig(m.cfg)
-	m.virusMatcher.SetConfig(m.cfg)
+func (m matcher) MatchNamesDetailed(
+	ctx context.Context,
+	names []string,
+	opts ...config.Option,
+) (output.Output, error) {
+	m, release := m.acquire()
+	defer release()
+	m = m.forRequest(opts)
 
 	maxNum := MaxNamesNum
 
 	names = truncateNamesToMaxNumber(names, maxNum)
-	res := make([]mlib.Match, len(names))
+	res := make([]output.Match, len(names))
+	m.batch = newBatch(names)
+
+	chOut := make(chan matchOut)
+	j := m.pool.newJob(ctx, m, chOut)
+	go func() {
+		loadNames(m.pool, j, names)
+		m.pool.done(j)
+	}()
 
-	go loadNames(chIn, names)
-	for range m.cfg.JobsNum {
-		go m.matchWorker(chIn, chOut, &wgIn)
+	var errs []error
+	for r := range chOut {
+		res[r.index] = newMatch(r)
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+	}
+
+	if m.cfg.WithBatchContext {
+		m.applyBatchContext(res)
+	}
+
+	if err := ctx.Err(); err != nil {
+		errsNum := len(errs) + fillUnmatched(res, names, err)
+		return m.prepareOutput(res, errsNum), err
+	}
+
+	out := m.prepareOutput(res, len(errs))
+	if len(errs) > 0 {
+		err := fmt.Errorf(
+			"matching failed for %d name(s): %w", len(errs), errs[0],
+		)
+		return out, err
 	}
+	return out, nil
+}
 
+func (m matcher) MatchStream(
+	ctx context.Context,
+	chNames <-chan string,
+	opts ...config.Option,
+) <-chan output.Match {
+	m, release := m.acquire()
+	m = m.forRequest(opts)
+	// a stream has no batch of names that could be used as the context.
+	m.cfg.WithBatchContext = false
+
+	// Workers never wait for orderMatches, because the window does not
+	// allow more names in work than the buffer of chOut can keep.
+	chOut := make(chan matchOut, streamWindow)
+	chRes := make(chan output.Match)
+	window := make(chan struct{}, streamWindow)
+
+	j := m.pool.newJob(ctx, m, chOut)
 	go func() {
-		defer wgOut.Done()
-		for r := range chOut {
-			res[r.index] = r.match
-		}
+		streamNames(ctx, chNames, m.pool, j, window)
+		m.pool.done(j)
+	}()
+
+	go func() {
+		defer release()
+		m.orderMatches(ctx, chOut, chRes, window)
 	}()
+	return chRes
+}
+
+// forRequest returns a copy of the matcher with per-request options
+// applied to its configuration. Exact, fuzzy and virus matchers are shared
+// by all requests and do not keep any request settings, the settings they
+// need are passed to them with every call.
+func (m matcher) forRequest(opts []config.Option) matcher {
+	cfg := m.cfg
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	m.cfg = cfg
+	m.ocr = nil
+	if cfg.WithOCRDistance {
+		m.ocr = fuzzy.NewOCR(cfg.OCRConfusions)
+	}
+	return m
+}
+
+// editDistance calculates edit distance between two strings with checks
+// from fuzzy.EditDistanceReason. If OCR distance is requested, it uses
+// weighted distance of the OCR model.
+func (m matcher) editDistance(s1, s2 string) (int, string) {
+	relax := m.cfg.WithRelaxedFuzzyMatch
+	if m.ocr != nil {
+		return m.ocr.EditDistanceReason(s1, s2, relax)
+	}
+	return fuzzy.EditDistanceReason(s1, s2, relax)
+}
+
+// fuzzyOpts returns settings of the request that are used by the fuzzy
+// matcher for the given stem.
+func (m matcher) fuzzyOpts(stem string) fuzzy.Options {
+	return fuzzy.Options{MaxEditDist: m.maxEditDist(stem)}
+}
+
+// maxEditDist returns the maximal edit distance allowed for the stem. With
+// adaptive edit distance it depends on the length and the number of words
+// of the stem.
+func (m matcher) maxEditDist(stem string) int {
+	wordsNum := strings.Count(stem, " ") + 1
+	return m.cfg.MaxEditDistFor(utf8.RuneCountInString(stem), wordsNum)
+}
 
-	wgIn.Wait()
-	close(chOut)
-	wgOut.Wait()
+// orderMatches receives results from workers, and sends them to chRes
+// in the same order as the names came in. Every sent result frees a slot
+// in the window, allowing to take a new name from the input.
+func (m matcher) orderMatches(
+	ctx context.Context,
+	chOut <-chan matchOut,
+	chRes chan<- output.Match,
+	window <-chan struct{},
+) {
+	defer close(chRes)
+	pending := make(map[int]output.Match)
+	var next int
+	for r := range chOut {
+		if ctx.Err() != nil {
+			continue
+		}
+		pending[r.index] = newMatch(r)
+		for {
+			match, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			m.setDataSources(&match.Match)
+			select {
+			case <-ctx.Done():
+			case chRes <- match:
+			}
+			<-window
+			next++
+		}
+	}
+}
 
-	return m.prepareOutput(res)
+// newMatch converts worker's result into output.Match.
+func newMatch(r matchOut) output.Match {
+	res := output.Match{
+		Match:             r.match,
+		ExtendedMatchType: r.extType,
+		ItemsDetails:      r.details,
+		Explanation:       r.explanation,
+	}
+	if r.err != nil {
+		res.Error = r.err.Error()
+	}
+	return res
 }
 
-func (m matcher) prepareOutput(ms []mlib.Match) mlib.Output {
-	res := mlib.Output{
+func (m matcher) prepareOutput(ms []output.Match, errsNum int) output.Output {
+	res := output.Output{
 		Meta: mlib.Meta{
 			NamesNum:                len(ms),
 			WithSpeciesGroup:        m.cfg.WithSpeciesGroup,
 			WithUninomialFuzzyMatch: m.cfg.WithUninomialFuzzyMatch,
 			DataSources:             m.cfg.DataSources,
 		},
+		ErrorsNum: errsNum,
 	}
 	for i := range ms {
-		for ii := range ms[i].MatchItems {
-			ms[i].MatchItems[ii].DataSources =
-				m.convertDataSources(ms[i].MatchItems[ii])
-		}
+		m.setDataSources(&ms[i].Match)
 	}
 	res.Matches = ms
 	return res
 }
 
+// setDataSources converts data-sources map of match items to a sorted
+// slice of data-source IDs.
+func (m matcher) setDataSources(match *mlib.Match) {
+	for i := range match.MatchItems {
+		match.MatchItems[i].DataSources =
+			m.convertDataSources(match.MatchItems[i])
+	}
+}
+
 func (m matcher) convertDataSources(mi mlib.MatchItem) []int {
 	if len(m.cfg.DataSources) == 0 {
 		res := make([]int, len(mi.DataSourcesMap))
@@ -164,91 +369,234 @@ func (m matcher) convertDataSources(mi mlib.MatchItem) []int {
 	return res
 }
 
-// matchWorker takes name-strings from chIn channel, matches them
-// and sends results to chOut channel.
-func (m matcher) matchWorker(
-	chIn <-chan nameIn,
-	chOut chan<- matchOut,
-	wg *sync.WaitGroup,
-) error {
-	var err error
-	gnpCfg := gnparser.NewConfig()
-	parser := gnparser.New(gnpCfg)
-	defer wg.Done()
-
-	for tsk := range chIn {
-		var matchResult *mlib.Match
-		ns, prsd := newNameString(parser, tsk.name)
-
-		var nsSpGr *nameString
-		if m.cfg.WithSpeciesGroup {
-			nsSpGr = ns.spGroupString(parser)
+// matchName runs all matching stages for one name-string, and ranks the
+// found match items. In explain mode the result contains the explanation
+// of matching. If matching fails, the result is NoMatch and contains the
+// error. Panics are converted to errors, so one bad record would not stop
+// matching of other names.
+func (m matcher) matchName(
+	parser gnparser.GNparser,
+	name string,
+) (res matchOut) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = matchOut{
+				match: unmatched(name),
+				err:   fmt.Errorf("panic: %v", r),
+			}
 		}
+	}()
 
-		if prsd.Parsed {
-			if abbrResult := detectAbbreviated(prsd); abbrResult != nil {
-				chOut <- matchOut{index: tsk.index, match: *abbrResult}
-				continue
+	// ChangeConfig returns a copy, the parser of the worker stays the same.
+	if m.cfg.NomCode != nomcode.Unknown {
+		parser = parser.ChangeConfig(gnparser.OptCode(m.cfg.NomCode))
+	}
+	ns, prsd := newNameString(parser, name, m.cfg.NomCode)
+	if m.cfg.WithExplain {
+		m.trace = newTrace(ns)
+	}
+	match, err := m.matchStages(parser, ns, prsd)
+	if err != nil {
+		return matchOut{
+			match:       unmatched(name),
+			explanation: m.trace.explanation(),
+			err:         err,
+		}
+	}
+	// hybrid formulas that are not found as a whole are matched by parents
+	if isHybridFormula(prsd) &&
+		(match.MatchType == vlib.NoMatch || isPartial(match.MatchType)) {
+		hybridMatch, details, err := m.matchHybrid(parser, ns)
+		if err != nil {
+			return matchOut{
+				match:       unmatched(name),
+				explanation: m.trace.explanation(),
+				err:         err,
 			}
-			matchResult, err = m.matchStem(ns)
-			if err != nil {
-				return err
+		}
+		if hybridMatch.MatchType != vlib.NoMatch {
+			return matchOut{
+				match:       hybridMatch,
+				extType:     output.HybridFormula,
+				details:     details,
+				explanation: m.trace.explanation(),
 			}
+		}
+	}
+	details := m.rankItems(ns, &match)
+	return matchOut{
+		match:       match,
+		extType:     extendedMatchType(details),
+		details:     details,
+		explanation: m.trace.explanation(),
+	}
+}
 
-			// if we are matching a whole species group, add group's
-			// data to the match.
-			if nsSpGr != nil {
-				spGrResult, err := m.matchStem(*nsSpGr)
-				if err != nil {
-					return err
-				}
-				ns.fixSpGrResult(spGrResult)
-				if matchResult == nil {
-					matchResult = spGrResult
-				} else if spGrResult != nil {
-					matchResult.MatchItems = append(
-						matchResult.MatchItems,
-						spGrResult.MatchItems...,
-					)
-				}
-			}
+// matchStages tries matching stages one after another, until one of them
+// finds a match.
+func (m matcher) matchStages(
+	parser gnparser.GNparser,
+	ns nameString,
+	prsd *parsed.Parsed,
+) (mlib.Match, error) {
+	var matchResult *mlib.Match
+	var err error
 
-			if ns.Cardinality < 2 && !m.cfg.WithUninomialFuzzyMatch {
-				if matchResult == nil {
-					matchResult = emptyResult(ns)
-				}
-				chOut <- matchOut{index: tsk.index, match: *matchResult}
-				continue
-			}
-		} else if ns.IsVirus {
-			matchResult, err = m.matchVirus(ns)
+	if prsd.Parsed {
+		st := m.trace.begin(stageAbbreviation, ns.Name)
+		abbrResult := detectAbbreviated(prsd)
+		st.end(abbrResult)
+		if abbrResult != nil {
+			matchResult, err = m.matchAbbreviated(ns)
 			if err != nil {
-				return err
+				return mlib.Match{}, err
+			}
+			if matchResult != nil {
+				return *matchResult, nil
 			}
+			return *abbrResult, nil
 		}
-		if matchResult == nil {
-			matchResult, err = m.matchFuzzy(ns.Canonical, ns.CanonicalStem, ns)
+		matchResult, err = m.matchStem(ns, stageExact)
+		if err != nil {
+			return mlib.Match{}, err
+		}
+
+		// if we are matching a whole species group, add group's
+		// data to the match.
+		if m.cfg.WithSpeciesGroup {
+			spGrResult, err := m.matchSpeciesGroup(parser, ns)
 			if err != nil {
-				return err
+				return mlib.Match{}, err
+			}
+			if matchResult == nil {
+				matchResult = spGrResult
+			} else if spGrResult != nil {
+				matchResult.MatchItems = append(
+					matchResult.MatchItems,
+					spGrResult.MatchItems...,
+				)
 			}
 		}
-		if matchResult == nil {
-			matchResult, err = m.matchPartial(ns, parser)
-			if err != nil {
-				return err
+
+		if ns.Cardinality < 2 && !m.cfg.WithUninomialFuzzyMatch {
+			if matchResult == nil && m.cfg.WithWordsChange {
+				matchResult, err = m.matchWords(ns)
+				if err != nil {
+					return mlib.Match{}, err
+				}
+			}
+			if matchResult == nil {
+				matchResult = emptyResult(ns)
 			}
+			return *matchResult, nil
+		}
+	} else if ns.IsVirus {
+		matchResult, err = m.matchVirus(ns)
+		if err != nil {
+			return mlib.Match{}, err
 		}
-		chOut <- matchOut{index: tsk.index, match: *matchResult}
 	}
-	return nil
+	if matchResult == nil {
+		matchResult, err = m.matchFuzzy(ns.Canonical, ns.CanonicalStem, ns)
+		if err != nil {
+			return mlib.Match{}, err
+		}
+	}
+	if matchResult == nil && prsd.Parsed && m.cfg.WithWordsChange {
+		matchResult, err = m.matchWords(ns)
+		if err != nil {
+			return mlib.Match{}, err
+		}
+	}
+	if matchResult == nil {
+		matchResult, err = m.matchPartial(ns, parser)
+		if err != nil {
+			return mlib.Match{}, err
+		}
+	}
+	if prsd.Parsed && (matchResult.MatchType == vlib.NoMatch ||
+		genusOnly(matchResult)) {
+		epResult, err := m.matchEpithet(ns)
+		if err != nil {
+			return mlib.Match{}, err
+		}
+		if epResult != nil {
+			matchResult = epResult
+		}
+	}
+	return *matchResult, nil
 }
 
-func loadNames(chIn chan<- nameIn, names []string) {
+// loadNames adds names to the job in the pool. It stops if the context
+// of the job is canceled.
+func loadNames(p *pool, j *job, names []string) {
 	for i, name := range names {
-		ni := nameIn{index: i, name: name}
-		chIn <- ni
+		if !p.add(j, nameIn{index: i, name: name}) {
+			return
+		}
+	}
+}
+
+// streamNames reads names from chNames and adds them to the job in the
+// pool, assigning each name its position in the input. It waits for a free
+// slot in the window before taking every name, so a slow reader of the
+// results slows down reading of the input.
+func streamNames(
+	ctx context.Context,
+	chNames <-chan string,
+	p *pool,
+	j *job,
+	window chan<- struct{},
+) {
+	var i int
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case window <- struct{}{}:
+		}
+
+		var name string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case name, ok = <-chNames:
+			if !ok {
+				return
+			}
+		}
+
+		if !p.add(j, nameIn{index: i, name: name}) {
+			return
+		}
+		i++
+	}
+}
+
+// fillUnmatched sets NoMatch results with the given error for names that
+// were not processed because matching was canceled. It returns the number
+// of such names.
+func fillUnmatched(res []output.Match, names []string, err error) int {
+	var count int
+	for i := range res {
+		if res[i].ID != "" {
+			continue
+		}
+		res[i] = output.Match{Match: unmatched(names[i]), Error: err.Error()}
+		count++
+	}
+	return count
+}
+
+// unmatched creates NoMatch result for a name-string that was not
+// processed by matching stages.
+func unmatched(name string) mlib.Match {
+	return mlib.Match{
+		ID:        gnuuid.New(name).String(),
+		Name:      name,
+		MatchType: vlib.NoMatch,
 	}
-	close(chIn)
 }
 
 func truncateNamesToMaxNumber(names []string, maxNum int) []string {
@@ -305,7 +653,12 @@ func emptyResult(ns nameString) *mlib.Match {
 	}
 }
 
-func (m matcher) filterDataSources(mis []mlib.MatchItem) []mlib.MatchItem {
+// filterDataSources removes match items that are not found in requested
+// data-sources. Removed items are recorded by the stage trace.
+func (m matcher) filterDataSources(
+	mis []mlib.MatchItem,
+	st *stageTrace,
+) []mlib.MatchItem {
 	if len(mis) == 0 || len(m.cfg.DataSources) == 0 {
 		return mis
 	}
@@ -316,8 +669,9 @@ func (m matcher) filterDataSources(mis []mlib.MatchItem) []mlib.MatchItem {
 		if len(dataSourcesMap) > 0 {
 			mis[i].DataSourcesMap = dataSourcesMap
 			res = append(res, mis[i])
-
+			continue
 		}
+		st.reject(mis[i].MatchStr, reasonDataSources)
 	}
 	return res
 }
@@ -0,0 +1,244 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
+	"github.com/gnames/gnmatcher/internal/io/memio"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+type virusMatcherMock struct{}
+
+func (virusMatcherMock) Init() error { return nil }
+func (virusMatcherMock) MatchVirus(s string) ([]mlib.MatchItem, error) {
+	return nil, nil
+}
+func (virusMatcherMock) MatchVirusFuzzy(s string) ([]mlib.MatchItem, error) {
+	return nil, nil
+}
+func (virusMatcherMock) NameToBytes(s string) []byte { return []byte(s) }
+
+func mockMatcher() matcher {
+	cfg := config.New()
+	return matcher{
+		exactMatcher: exactMatcherMock{},
+		fuzzyMatcher: fuzzyMatcherMock{},
+		virusMatcher: virusMatcherMock{},
+		cfg:          cfg,
+		pool:         newPool(cfg.JobsNum, cfg.QueueSize),
+	}
+}
+
+// memMatcher creates a matcher that keeps lookup data for the given
+// canonical forms and viruses in memory. The matcher is closed after the
+// test.
+func memMatcher(t *testing.T, canonicals, viruses []memio.Name) Matcher {
+	data := memio.NewProvider(canonicals, viruses)
+	m := NewMatcher(
+		memio.NewExactMatcher(data),
+		memio.NewFuzzyMatcher(data),
+		memio.NewVirusMatcher(data),
+		config.New(),
+	)
+	assert.Nil(t, m.Init())
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+// memNames creates names for memMatcher that are found in the data-source
+// with ID 1.
+func memNames(names ...string) []memio.Name {
+	res := make([]memio.Name, len(names))
+	for i, v := range names {
+		res[i] = memio.Name{Name: v, DataSources: []int{1}}
+	}
+	return res
+}
+
+// TestMatchNamesCtxCanceled checks that canceled context stops matching
+// and that all names still get a result.
+func TestMatchNamesCtxCanceled(t *testing.T) {
+	assert := assert.New(t)
+	names := []string{"Pardosa maesta", "Acacia may", "Bubo bubo"}
+	m := mockMatcher()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := m.MatchNamesCtx(ctx, names)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal(len(names), res.NamesNum)
+	for i, v := range res.Matches {
+		assert.Equal(names[i], v.Name)
+		assert.NotEmpty(v.ID)
+		assert.Equal(vlib.NoMatch, v.MatchType)
+	}
+}
+
+func TestMatchNamesCtx(t *testing.T) {
+	assert := assert.New(t)
+	names := []string{"Pardosa maesta", "Acacia may"}
+	m := mockMatcher()
+
+	res, err := m.MatchNamesCtx(context.Background(), names)
+	assert.Nil(err)
+	assert.Equal(2, len(res.Matches))
+	assert.Equal(vlib.Fuzzy, res.Matches[0].MatchType)
+	assert.Equal("Pardosa moesta", res.Matches[0].MatchItems[0].MatchStr)
+}
+
+// TestMatchStream checks that streamed results keep the order of input.
+func TestMatchStream(t *testing.T) {
+	assert := assert.New(t)
+	names := make([]string, 0, 3*streamWindow)
+	for len(names) < cap(names) {
+		names = append(names, "Pardosa maesta", "Acacia may", "Not a name")
+	}
+	m := mockMatcher()
+
+	chNames := make(chan string)
+	go func() {
+		for _, v := range names {
+			chNames <- v
+		}
+		close(chNames)
+	}()
+
+	var res []output.Match
+	for v := range m.MatchStream(context.Background(), chNames) {
+		res = append(res, v)
+	}
+	assert.Equal(len(names), len(res))
+	for i := range res {
+		assert.Equal(names[i], res[i].Name)
+	}
+	assert.Equal(vlib.Fuzzy, res[0].MatchType)
+	assert.Equal(vlib.NoMatch, res[1].MatchType)
+}
+
+// errFuzzyMatcherMock fails to get match items for any stem, emulating
+// a corrupted key-value store.
+type errFuzzyMatcherMock struct {
+	fuzzyMatcherMock
+}
+
+func (errFuzzyMatcherMock) StemToMatchItems(
+	stem string,
+) ([]mlib.MatchItem, error) {
+	return nil, errors.New("corrupted value")
+}
+
+// TestMatchNamesDetailedErrors checks that errors are reported per name,
+// and do not prevent matching of other names.
+func TestMatchNamesDetailedErrors(t *testing.T) {
+	assert := assert.New(t)
+	names := []string{"Pardosa maesta", "Not a name", "Acacia may"}
+	m := mockMatcher()
+	m.fuzzyMatcher = errFuzzyMatcherMock{}
+
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.NotNil(err)
+	assert.Equal(1, res.ErrorsNum)
+	assert.Equal(len(names), len(res.Matches))
+	assert.Contains(res.Matches[0].Error, "corrupted value")
+	assert.Equal(vlib.NoMatch, res.Matches[0].MatchType)
+	assert.Equal(names[0], res.Matches[0].Name)
+	assert.NotEmpty(res.Matches[0].ID)
+	assert.Empty(res.Matches[1].Error)
+	assert.Empty(res.Matches[2].Error)
+	assert.Equal(names[2], res.Matches[2].Name)
+}
+
+// TestReload checks that new requests use reloaded matchers, while
+// the previous matchers are kept until requests that use them are done.
+func TestReload(t *testing.T) {
+	assert := assert.New(t)
+	names := []string{"Pardosa maesta"}
+	m := NewMatcher(
+		exactMatcherMock{}, fuzzyMatcherMock{}, virusMatcherMock{}, config.New(),
+	)
+
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+	assert.Equal(vlib.Fuzzy, res.Matches[0].MatchType)
+
+	old, release := m.(matcher).acquire()
+	released, err := m.Reload(
+		exactMatcherMock{}, errFuzzyMatcherMock{}, virusMatcherMock{},
+	)
+	assert.Nil(err)
+
+	res, err = m.MatchNamesDetailed(context.Background(), names)
+	assert.NotNil(err)
+	assert.Contains(res.Matches[0].Error, "corrupted value")
+
+	select {
+	case <-released:
+		t.Fatal("previous matchers are released while in use")
+	default:
+	}
+	release()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("previous matchers are not released")
+	}
+	old.pool.mu.Lock()
+	assert.True(old.pool.closed)
+	old.pool.mu.Unlock()
+	assert.Nil(m.Close())
+}
+
+// editDistFuzzyMatcherMock finds stems only if the request allows edit
+// distance 2.
+type editDistFuzzyMatcherMock struct {
+	fuzzyMatcherMock
+}
+
+func (fm editDistFuzzyMatcherMock) MatchStem(
+	stem string,
+	opts fuzzy.Options,
+) []string {
+	if opts.MaxEditDist < 2 {
+		return nil
+	}
+	return fm.fuzzyMatcherMock.MatchStem(stem, opts)
+}
+
+// TestRequestOptionsIsolation checks that concurrent requests with
+// different options do not get settings of each other.
+func TestRequestOptionsIsolation(t *testing.T) {
+	assert := assert.New(t)
+	names := []string{"Pardosa maesta"}
+	m := NewMatcher(
+		exactMatcherMock{}, editDistFuzzyMatcherMock{}, virusMatcherMock{},
+		config.New(),
+	)
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dist, matchType := 1, vlib.NoMatch
+			if i%2 == 0 {
+				dist, matchType = 2, vlib.Fuzzy
+			}
+			res := m.MatchNames(names,
+				config.OptMaxEditDist(dist),
+				config.OptWithUninomialFuzzyMatch(dist == 2),
+			)
+			assert.Equal(matchType, res.Matches[0].MatchType)
+			assert.Equal(dist == 2, res.WithUninomialFuzzyMatch)
+		}()
+	}
+	wg.Wait()
+}
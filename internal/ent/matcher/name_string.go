@@ -3,8 +3,7 @@ package matcher
 import (
 	"strings"
 
-	mlib "github.com/gnames/gnlib/ent/matcher"
-	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnlib/ent/nomcode"
 	"github.com/gnames/gnparser"
 	"github.com/gnames/gnparser/ent/parsed"
 	"github.com/gnames/gnuuid"
@@ -55,16 +54,19 @@ type partial struct {
 // multinomial contains multinomial names that were constructed from
 // an 'infraspecific' name-string.
 type multinomial struct {
-	// Tail is genus + the last epithet.
+	// Tail is genus + the last epithet. It is empty if the nomenclatural
+	// code does not allow such combinations.
 	Tail string
 	// Head is the name without the last epithet.
 	Head string
 }
 
-// newNameString creates a new instance of NameString.
+// newNameString creates a new instance of NameString. The parser has to be
+// configured for the nomenclatural code.
 func newNameString(
 	parser gnparser.GNparser,
 	name string,
+	code nomcode.Code,
 ) (nameString, *parsed.Parsed) {
 	prsd := parser.ParseName(name)
 	if prsd.Parsed {
@@ -77,7 +79,7 @@ func newNameString(
 			CanonicalStem:   prsd.Canonical.Stemmed,
 		}
 
-		ns.newPartial(prsd)
+		ns.newPartial(prsd, code)
 		return ns, &prsd
 	}
 
@@ -88,72 +90,67 @@ func newNameString(
 	}, &prsd
 }
 
-func (ns *nameString) spGroupString(parser gnparser.GNparser) *nameString {
-	switch ns.Cardinality {
-	case 2:
-		return ns.getTrinomial(parser)
-	case 3:
-		return ns.getBinomial(parser)
-	}
-	return nil
-}
-
-func (ns *nameString) getTrinomial(parser gnparser.GNparser) *nameString {
+// spGroupStrings returns names of the species group of the name-string.
+// Binomials get autonyms of infraspecific taxa, like 'Aus bus bus' and
+// 'Aus bus bus bus'. Canonical forms do not keep ranks, so they cover
+// 'subsp.' and 'var.' autonyms of botany, as well as nominotypical
+// subspecies of zoology. Infraspecific names of any cardinality get their
+// parent species.
+func (ns *nameString) spGroupStrings(
+	parser gnparser.GNparser,
+	code nomcode.Code,
+) []nameString {
 	words := strings.Split(ns.Canonical, " ")
-	if len(words) != 2 {
-		return nil
+	var names []string
+	switch {
+	case ns.Cardinality == 2 && len(words) == 2:
+		autonym := ns.Canonical + " " + words[1]
+		names = []string{autonym, autonym + " " + words[1]}
+	case ns.Cardinality > 2 && len(words) > 2:
+		names = []string{words[0] + " " + words[1]}
 	}
-	words = append(words, words[len(words)-1])
-	name := strings.Join(words, " ")
-	res, _ := newNameString(parser, name)
-	return &res
-}
 
-func (ns *nameString) getBinomial(parser gnparser.GNparser) *nameString {
-	words := strings.Split(ns.Canonical, " ")
-	if len(words) != 3 {
-		return nil
+	res := make([]nameString, 0, len(names))
+	for _, name := range names {
+		spGr, _ := newNameString(parser, name, code)
+		res = append(res, spGr)
 	}
-	name := strings.Join(words[0:2], " ")
-	res, _ := newNameString(parser, name)
-	return &res
+	return res
 }
 
-func (ns *nameString) fixSpGrResult(r *mlib.Match) {
-	if r == nil {
-		return
-	}
-	r.ID = ns.ID
-	r.Name = ns.Name
-	r.MatchType = vlib.ExactSpeciesGroup
-	for i := range r.MatchItems {
-		r.MatchItems[i].MatchType = vlib.ExactSpeciesGroup
-	}
-}
-
-func (ns *nameString) newPartial(prsd parsed.Parsed) {
+// newPartial creates truncated versions of the canonical form according to
+// the nomenclatural code. With the code of cultivated plants the first
+// version removes the cultivar epithet, that can contain several words.
+// Botanical codes do not allow to combine the genus with an infraspecific
+// epithet, so such multinomials have only heads.
+func (ns *nameString) newPartial(prsd parsed.Parsed, code nomcode.Code) {
 	if prsd.Cardinality < 2 {
 		return
 	}
-	canAry := strings.Split(ns.Canonical, " ")
+	canonical := ns.Canonical
+	var multinomials []multinomial
+	if code == nomcode.Cultivars {
+		if idx := strings.Index(canonical, " ‘"); idx != -1 {
+			canonical = canonical[:idx]
+			if strings.Contains(canonical, " ") {
+				multinomials = append(multinomials, multinomial{Head: canonical})
+			}
+		}
+	}
+	canAry := strings.Split(canonical, " ")
 
 	ns.Partial = &partial{Genus: canAry[0]}
-	partialNum := prsd.Cardinality - 2
+	withTail := code != nomcode.Botanical && code != nomcode.Cultivars
 
-	// In case of binomial we return only genus
-	if partialNum < 1 {
-		return
-	}
-
-	ns.Partial.Multinomials = make([]multinomial, partialNum)
-	for i := range ns.Partial.Multinomials {
-		lastLen := len(canAry) - i - 1
-		tail := []string{ns.Partial.Genus, canAry[lastLen]}
+	// binomials get only the genus
+	for lastLen := len(canAry) - 1; lastLen > 1; lastLen-- {
 		head := canAry[0:lastLen]
-
-		ns.Partial.Multinomials[i] = multinomial{
-			Tail: strings.Join(tail, " "),
-			Head: strings.Join(head, " "),
+		mn := multinomial{Head: strings.Join(head, " ")}
+		if withTail {
+			tail := []string{ns.Partial.Genus, canAry[lastLen]}
+			mn.Tail = strings.Join(tail, " ")
 		}
+		multinomials = append(multinomials, mn)
 	}
+	ns.Partial.Multinomials = multinomials
 }
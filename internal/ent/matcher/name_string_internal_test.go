@@ -0,0 +1,31 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/gnames/gnlib/ent/nomcode"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNomCode checks that the nomenclatural code of the request changes
+// parsing and partial matching of names.
+func TestNomCode(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, memNames("Sarracenia flava", "Pardosa moesta"), nil)
+
+	names := []string{"Sarracenia flava 'Maxima'", "Pardosa lugubris var. moesta"}
+	res := m.MatchNames(names)
+	assert.Equal(vlib.Exact, res.Matches[0].MatchType)
+	assert.Equal("Sarracenia flava", res.Matches[0].MatchItems[0].MatchStr)
+	assert.Equal(vlib.PartialExact, res.Matches[1].MatchType)
+	assert.Equal("Pardosa moesta", res.Matches[1].MatchItems[0].MatchStr)
+
+	res = m.MatchNames(names, config.OptNomCode(nomcode.Cultivars))
+	assert.Equal(vlib.PartialExact, res.Matches[0].MatchType)
+	assert.Equal("Sarracenia flava", res.Matches[0].MatchItems[0].MatchStr)
+
+	res = m.MatchNames(names, config.OptNomCode(nomcode.Botanical))
+	assert.Equal(vlib.NoMatch, res.Matches[1].MatchType)
+}
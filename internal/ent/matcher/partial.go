@@ -1,9 +1,10 @@
 package matcher
 
 import (
+	"strings"
+
 	mlib "github.com/gnames/gnlib/ent/matcher"
 	vlib "github.com/gnames/gnlib/ent/verifier"
-	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
 	"github.com/gnames/gnparser"
 	"github.com/gnames/gnparser/ent/stemmer"
 	"github.com/gnames/gnuuid"
@@ -20,12 +21,16 @@ func (m matcher) matchPartial(
 	if ns.Partial == nil {
 		return emptyResult(ns), nil
 	}
+	if m.cfg.WithAllPartials {
+		return m.matchAllPartials(ns, parser)
+	}
 
 	for _, partial := range ns.Partial.Multinomials {
-		if res, err = m.processPartial(partial, ns, parser); res != nil {
-			if err != nil {
-				return nil, err
-			}
+		res, err = m.processPartial(partial, ns, parser)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
 			return res, nil
 		}
 	}
@@ -36,7 +41,12 @@ func (m matcher) matchPartial(
 	return res, nil
 }
 
-func (m matcher) processPartialGenus(ns nameString) (*mlib.Match, error) {
+func (m matcher) processPartialGenus(
+	ns nameString,
+) (res *mlib.Match, err error) {
+	st := m.trace.begin(stagePartialGenus, ns.Partial.Genus)
+	defer func() { st.end(res) }()
+
 	gID := gnuuid.New(ns.Partial.Genus).String()
 	matchItems, err := m.exactStemMatches(gID, ns.Partial.Genus)
 	if err != nil {
@@ -48,11 +58,11 @@ func (m matcher) processPartialGenus(ns nameString) (*mlib.Match, error) {
 		fuzzyMatchType = vlib.PartialFuzzyRelaxed
 	}
 
-	matchItems = m.filterDataSources(matchItems)
+	matchItems = m.filterDataSources(matchItems, st)
 
 	if len(matchItems) == 0 && m.cfg.WithUninomialFuzzyMatch {
 		gen := ns.Partial.Genus
-		res, err := m.matchFuzzy(gen, gen, ns)
+		res, err = m.matchFuzzy(gen, gen, ns)
 		if err != nil {
 			return nil, err
 		}
@@ -74,7 +84,7 @@ func (m matcher) processPartialGenus(ns nameString) (*mlib.Match, error) {
 		matchItems[i].InputStr = ns.Partial.Genus
 		matchItems[i].MatchType = vlib.PartialExact
 	}
-	res := &mlib.Match{
+	res = &mlib.Match{
 		ID:         ns.ID,
 		Name:       ns.Name,
 		MatchType:  vlib.PartialExact,
@@ -85,76 +95,210 @@ func (m matcher) processPartialGenus(ns nameString) (*mlib.Match, error) {
 
 func (m matcher) processPartial(p multinomial, ns nameString,
 	parser gnparser.GNparser) (*mlib.Match, error) {
-	names := []string{p.Tail, p.Head}
-	relax := m.cfg.WithRelaxedFuzzyMatch
-
-	fuzzyMatchType := vlib.PartialFuzzy
-	if relax {
-		fuzzyMatchType = vlib.PartialFuzzyRelaxed
+	names := partialNames(p)
+	for _, name := range names {
+		res, found, err := m.partialExact(name, ns, parser)
+		if err != nil || found {
+			return res, err
+		}
 	}
 
+	// if exact partial failed, try fuzzy
 	for _, name := range names {
-		nsPart, parsed := newNameString(parser, name)
-		if !parsed.Parsed {
-			continue
-		}
-		matches, err := m.exactStemMatches(nsPart.CanonicalStemID, nsPart.CanonicalStem)
-		if err != nil {
-			return nil, err
+		res, found, err := m.partialFuzzy(name, ns)
+		if err != nil || found {
+			return res, err
 		}
-		if len(matches) > 0 {
-			matchItems := make([]mlib.MatchItem, 0, len(matches))
-			for _, v := range matches {
-				v.InputStr = nsPart.Canonical
-				if v.MatchStr == v.InputStr {
-					fuzzyMatchType = vlib.PartialExact
-					v.MatchType = fuzzyMatchType
-				} else {
-					editDistance := fuzzy.EditDistance(v.InputStr, v.MatchStr, relax)
-					if editDistance == -1 {
-						continue
-					}
-					v.EditDistance = editDistance
-					v.MatchType = fuzzyMatchType
+	}
+
+	return nil, nil
+}
+
+// matchAllPartials is used instead of matchPartial if all partial matches
+// are requested. It does not stop at the first match, and returns match
+// items of every head and tail of multinomials, and of the genus. Details
+// of such items tell which words were removed from the canonical form.
+func (m matcher) matchAllPartials(
+	ns nameString,
+	parser gnparser.GNparser,
+) (*mlib.Match, error) {
+	var matchItems []mlib.MatchItem
+	for _, p := range ns.Partial.Multinomials {
+		for _, name := range partialNames(p) {
+			res, found, err := m.partialExact(name, ns, parser)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				res, _, err = m.partialFuzzy(name, ns)
+				if err != nil {
+					return nil, err
 				}
-				matchItems = append(matchItems, v)
 			}
-
-			matchItems = m.filterDataSources(matchItems)
-			if len(matchItems) == 0 {
-				return nil, nil
+			if res != nil {
+				matchItems = append(matchItems, res.MatchItems...)
 			}
+		}
+	}
 
-			res := &mlib.Match{
-				ID:         ns.ID,
-				Name:       ns.Name,
-				MatchType:  fuzzyMatchType,
-				MatchItems: matchItems,
-			}
-			return res, nil
+	res, err := m.processPartialGenus(ns)
+	if err != nil {
+		return nil, err
+	}
+	matchItems = append(matchItems, res.MatchItems...)
+	if len(matchItems) == 0 {
+		return emptyResult(ns), nil
+	}
+
+	matchType := vlib.PartialFuzzy
+	if m.cfg.WithRelaxedFuzzyMatch {
+		matchType = vlib.PartialFuzzyRelaxed
+	}
+	for _, v := range matchItems {
+		if v.MatchType == vlib.PartialExact {
+			matchType = vlib.PartialExact
+			break
 		}
 	}
+	res = &mlib.Match{
+		ID:         ns.ID,
+		Name:       ns.Name,
+		MatchType:  matchType,
+		MatchItems: matchItems,
+	}
+	return res, nil
+}
 
-	// if exact partial failed, try fuzzy
-	for _, name := range names {
-		stem := stemmer.Stem(name).Stem
-		if res, err := m.matchFuzzy(name, stem, ns); res != nil {
-			if err != nil {
-				return nil, err
-			}
-			res.MatchItems = m.filterDataSources(res.MatchItems)
-			if len(res.MatchItems) == 0 {
-				return nil, nil
-			}
+// genusOnly checks if a partial match found only the genus of the name,
+// so the match does not tell anything about the species. The epithet
+// stage can find a better match for such names.
+func genusOnly(match *mlib.Match) bool {
+	if !isPartial(match.MatchType) {
+		return false
+	}
+	for _, v := range match.MatchItems {
+		if strings.Contains(v.InputStr, " ") {
+			return false
+		}
+	}
+	return true
+}
 
-			for i := range res.MatchItems {
-				res.MatchItems[i].MatchType = fuzzyMatchType
-			}
+// removedWords returns words of the canonical form that are absent in the
+// truncated input of a partial match item.
+func removedWords(canonical, input string) []string {
+	kept := strings.Fields(input)
+	var res []string
+	for _, w := range strings.Fields(canonical) {
+		if len(kept) > 0 && kept[0] == w {
+			kept = kept[1:]
+			continue
+		}
+		res = append(res, w)
+	}
+	return res
+}
 
-			res.MatchType = fuzzyMatchType
-			return res, nil
+// partialNames returns the tail and the head of a multinomial. Tails can
+// be empty, if the nomenclatural code does not allow them.
+func partialNames(p multinomial) []string {
+	if p.Tail == "" {
+		return []string{p.Head}
+	}
+	return []string{p.Tail, p.Head}
+}
+
+// partialExact tries to match a truncated name exactly by its stem. It
+// returns true, if the stem was found, even if its items were removed by
+// data-sources filter.
+func (m matcher) partialExact(
+	name string,
+	ns nameString,
+	parser gnparser.GNparser,
+) (*mlib.Match, bool, error) {
+	fuzzyMatchType := vlib.PartialFuzzy
+	if m.cfg.WithRelaxedFuzzyMatch {
+		fuzzyMatchType = vlib.PartialFuzzyRelaxed
+	}
+
+	st := m.trace.begin(stagePartial, name)
+	nsPart, parsed := newNameString(parser, name, m.cfg.NomCode)
+	if !parsed.Parsed {
+		st.end(nil)
+		return nil, false, nil
+	}
+	matches, err := m.exactStemMatches(nsPart.CanonicalStemID, nsPart.CanonicalStem)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(matches) == 0 {
+		st.end(nil)
+		return nil, false, nil
+	}
+
+	matchItems := make([]mlib.MatchItem, 0, len(matches))
+	for _, v := range matches {
+		v.InputStr = nsPart.Canonical
+		if v.MatchStr == v.InputStr {
+			fuzzyMatchType = vlib.PartialExact
+			v.MatchType = fuzzyMatchType
+		} else {
+			editDistance, reason := m.editDistance(v.InputStr, v.MatchStr)
+			if editDistance == -1 {
+				st.reject(v.MatchStr, reason)
+				continue
+			}
+			v.EditDistance = editDistance
+			v.MatchType = fuzzyMatchType
 		}
+		matchItems = append(matchItems, v)
 	}
 
-	return nil, nil
+	matchItems = m.filterDataSources(matchItems, st)
+	if len(matchItems) == 0 {
+		st.end(nil)
+		return nil, true, nil
+	}
+
+	res := &mlib.Match{
+		ID:         ns.ID,
+		Name:       ns.Name,
+		MatchType:  fuzzyMatchType,
+		MatchItems: matchItems,
+	}
+	st.end(res)
+	return res, true, nil
+}
+
+// partialFuzzy tries to match a truncated name by fuzzy matching. It
+// returns true, if fuzzy matching found candidates, even if their items
+// were removed by data-sources filter.
+func (m matcher) partialFuzzy(
+	name string,
+	ns nameString,
+) (*mlib.Match, bool, error) {
+	fuzzyMatchType := vlib.PartialFuzzy
+	if m.cfg.WithRelaxedFuzzyMatch {
+		fuzzyMatchType = vlib.PartialFuzzyRelaxed
+	}
+
+	stem := stemmer.Stem(name).Stem
+	res, err := m.matchFuzzy(name, stem, ns)
+	if err != nil {
+		return nil, false, err
+	}
+	if res == nil {
+		return nil, false, nil
+	}
+
+	res.MatchItems = m.filterDataSources(res.MatchItems, nil)
+	if len(res.MatchItems) == 0 {
+		return nil, true, nil
+	}
+
+	for i := range res.MatchItems {
+		res.MatchItems[i].MatchType = fuzzyMatchType
+	}
+	res.MatchType = fuzzyMatchType
+	return res, true, nil
 }
@@ -1,18 +1,22 @@
 package matcher
 
 import (
+	"context"
 	"testing"
 
+	"github.com/gnames/gnlib/ent/nomcode"
 	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
 	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnparser"
 	"github.com/stretchr/testify/assert"
 )
 
 type exactMatcherMock struct{}
 
-func (exactMatcherMock) Init() error              { return nil }
-func (exactMatcherMock) SetConfig(cfg config.Config) {}
-func (exactMatcherMock) MatchCanonicalID(uuid string) bool { return false }
+func (exactMatcherMock) Init() error                               { return nil }
+func (exactMatcherMock) MatchCanonicalID(uuid string) bool         { return false }
+func (exactMatcherMock) AddStems(data provider.DataProvider) error { return nil }
 
 // TestProcessPartialGenusNoMatchReturnsEmptyResult verifies the fix for a
 // nil pointer dereference: when WithUninomialFuzzyMatch is true but fuzzy
@@ -38,3 +42,91 @@ func TestProcessPartialGenusNoMatchReturnsEmptyResult(t *testing.T) {
 	assert.Equal(t, ns.ID, res.ID)
 	assert.Equal(t, ns.Name, res.Name)
 }
+
+// TestPartialNomCode checks that partial versions of names depend on the
+// nomenclatural code.
+func TestPartialNomCode(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		msg, name string
+		code      nomcode.Code
+		res       []multinomial
+	}{
+		{"binomial", "Aus bus", nomcode.Unknown, nil},
+		{"unknown", "Aus bus var. cus", nomcode.Unknown,
+			[]multinomial{{Tail: "Aus cus", Head: "Aus bus"}}},
+		{"zoological", "Aus bus cus dus", nomcode.Zoological,
+			[]multinomial{
+				{Tail: "Aus dus", Head: "Aus bus cus"},
+				{Tail: "Aus cus", Head: "Aus bus"},
+			}},
+		{"botanical", "Aus bus var. cus", nomcode.Botanical,
+			[]multinomial{{Head: "Aus bus"}}},
+		{"cultivar", "Sarracenia flava 'Blue Moon'", nomcode.Cultivars,
+			[]multinomial{{Head: "Sarracenia flava"}}},
+		{"cultivar trinomial", "Aus bus var. cus 'Dus'", nomcode.Cultivars,
+			[]multinomial{{Head: "Aus bus cus"}, {Head: "Aus bus"}}},
+	}
+	for _, v := range tests {
+		parser := gnparser.New(gnparser.NewConfig(gnparser.OptCode(v.code)))
+		ns, _ := newNameString(parser, v.name, v.code)
+		assert.NotNil(ns.Partial, v.msg)
+		assert.Equal(v.res, ns.Partial.Multinomials, v.msg)
+	}
+}
+
+func TestRemovedWords(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		canonical, input string
+		res              []string
+	}{
+		{"Aus bus cus dus", "Aus bus cus", []string{"dus"}},
+		{"Aus bus cus dus", "Aus dus", []string{"bus", "cus"}},
+		{"Aus bus cus dus", "Aus", []string{"bus", "cus", "dus"}},
+		{"Aus bus bus", "Aus bus", []string{"bus"}},
+		{"Aus bus", "Aus bus", nil},
+	}
+	for _, v := range tests {
+		assert.Equal(v.res, removedWords(v.canonical, v.input), v.input)
+	}
+}
+
+// TestAllPartials checks that WithAllPartials returns items of all found
+// truncated versions of a name.
+func TestAllPartials(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, memNames(
+		"Pardosa",
+		"Pardosa moesta",
+		"Pardosa moesta major",
+		"Pardosa minor",
+	), nil)
+
+	names := []string{"Pardosa moesta major minor"}
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+	match := res.Matches[0]
+	assert.Equal(vlib.PartialExact, match.MatchType)
+	assert.Equal(1, len(match.MatchItems))
+	assert.Equal("Pardosa minor", match.MatchItems[0].MatchStr)
+	assert.Equal([]string{"moesta", "major"}, match.ItemsDetails[0].RemovedWords)
+
+	res, err = m.MatchNamesDetailed(context.Background(), names,
+		config.OptWithAllPartials(true))
+	assert.Nil(err)
+	match = res.Matches[0]
+	assert.Equal(vlib.PartialExact, match.MatchType)
+	assert.Equal(4, len(match.MatchItems))
+	removed := make(map[string][]string)
+	for i, v := range match.MatchItems {
+		removed[v.MatchStr] = match.ItemsDetails[i].RemovedWords
+	}
+	assert.Equal(map[string][]string{
+		"Pardosa moesta major": {"minor"},
+		"Pardosa minor":        {"moesta", "major"},
+		"Pardosa moesta":       {"major", "minor"},
+		"Pardosa":              {"moesta", "major", "minor"},
+	}, removed)
+	assert.Equal("Pardosa moesta major", match.MatchItems[0].MatchStr)
+}
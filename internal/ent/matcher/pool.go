@@ -0,0 +1,196 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/gnames/gnparser"
+)
+
+// pool is a set of long-lived workers that match names of all requests.
+// Every worker has its own parser, so requests do not pay for creation of
+// parsers. Workers take names from queues of requests in turn, so a large
+// request does not hold back small requests that come after it.
+type pool struct {
+	mu sync.Mutex
+
+	// names signals workers that a new name was added to a queue.
+	names *sync.Cond
+
+	// space signals requests that there is a free place in their queue, or
+	// that their context is canceled.
+	space *sync.Cond
+
+	// jobs are requests that use the pool now.
+	jobs []*job
+
+	// next is the index of the job that gives the next name to a worker.
+	next int
+
+	// queueSize is the maximal number of names of one request that wait
+	// for a worker.
+	queueSize int
+
+	// closed is true after close is called. Workers of a closed pool stop
+	// when all queues are empty.
+	closed bool
+
+	// workers counts running workers.
+	workers sync.WaitGroup
+}
+
+// job contains names of one request that wait for workers, and the
+// channel for their matches.
+type job struct {
+	ctx context.Context
+
+	// m is the matcher with settings and lookup data of the request.
+	m     matcher
+	queue []nameIn
+	out   chan<- matchOut
+
+	// inWork counts names that were added, but are not processed yet.
+	inWork sync.WaitGroup
+
+	// stop cancels notification about canceled context.
+	stop func() bool
+}
+
+// newPool creates a pool and starts its workers.
+func newPool(workersNum, queueSize int) *pool {
+	workersNum = max(workersNum, 1)
+	p := &pool{queueSize: max(queueSize, 1)}
+	p.names = sync.NewCond(&p.mu)
+	p.space = sync.NewCond(&p.mu)
+
+	for range workersNum {
+		parser := gnparser.New(gnparser.NewConfig())
+		p.workers.Go(func() { p.work(parser) })
+	}
+	return p
+}
+
+// newJob registers a request in the pool. Matches of its names are sent to
+// the out channel, that is closed by done.
+func (p *pool) newJob(
+	ctx context.Context,
+	m matcher,
+	out chan<- matchOut,
+) *job {
+	j := &job{ctx: ctx, m: m, out: out}
+	j.stop = context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		p.space.Broadcast()
+		p.mu.Unlock()
+	})
+
+	p.mu.Lock()
+	p.jobs = append(p.jobs, j)
+	p.mu.Unlock()
+	return j
+}
+
+// add puts a name to the queue of the job. If the queue is full, it waits
+// for a free place. It returns false if the context of the job is canceled,
+// or if the pool is closed.
+func (p *pool) add(j *job, ni nameIn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(j.queue) >= p.queueSize && j.ctx.Err() == nil && !p.closed {
+		p.space.Wait()
+	}
+	if j.ctx.Err() != nil || p.closed {
+		return false
+	}
+	j.queue = append(j.queue, ni)
+	j.inWork.Add(1)
+	p.names.Signal()
+	return true
+}
+
+// done is called after all names of the job are added. It waits until the
+// names are processed, removes the job from the pool and closes its
+// output channel.
+func (p *pool) done(j *job) {
+	j.inWork.Wait()
+	j.stop()
+
+	p.mu.Lock()
+	if idx := slices.Index(p.jobs, j); idx != -1 {
+		p.jobs = slices.Delete(p.jobs, idx, idx+1)
+	}
+	p.mu.Unlock()
+	close(j.out)
+}
+
+// close stops workers of the pool and waits until they exit. Names that
+// are already in the queues are processed, new names are not accepted.
+func (p *pool) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.names.Broadcast()
+	p.space.Broadcast()
+	p.mu.Unlock()
+	p.workers.Wait()
+}
+
+// take waits for a name in any of the queues, and returns it with its job.
+// Jobs give names in turn. Names of canceled jobs are discarded. It returns
+// nil job if the pool is closed and all queues are empty.
+func (p *pool) take() (*job, nameIn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		for range len(p.jobs) {
+			p.next %= len(p.jobs)
+			j := p.jobs[p.next]
+			p.next++
+			if len(j.queue) == 0 {
+				continue
+			}
+			if j.ctx.Err() != nil {
+				for range j.queue {
+					j.inWork.Done()
+				}
+				j.queue = nil
+				continue
+			}
+
+			ni := j.queue[0]
+			j.queue = j.queue[1:]
+			p.space.Broadcast()
+			return j, ni
+		}
+		if p.closed {
+			return nil, nameIn{}
+		}
+		p.names.Wait()
+	}
+}
+
+// work matches names from the queues of the pool. If matching of a name
+// fails, the error is sent together with the result, and the worker
+// continues with the next name. The worker stops when the pool is closed.
+func (p *pool) work(parser gnparser.GNparser) {
+	for {
+		j, ni := p.take()
+		if j == nil {
+			return
+		}
+		if j.ctx.Err() != nil {
+			j.inWork.Done()
+			continue
+		}
+		res := j.m.matchName(parser, ni.name)
+		if res.err != nil {
+			res.err = fmt.Errorf("cannot match '%s': %w", ni.name, res.err)
+			slog.Error("Matching failed", "error", res.err)
+		}
+		res.index = ni.index
+		j.out <- res
+		j.inWork.Done()
+	}
+}
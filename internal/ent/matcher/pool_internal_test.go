@@ -0,0 +1,109 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPoolFairness checks that a small request is not delayed until a
+// large request that came before it is finished.
+func TestPoolFairness(t *testing.T) {
+	assert := assert.New(t)
+	m := mockMatcher()
+	m.pool = newPool(1, 10)
+
+	large := make([]string, 2_000)
+	for i := range large {
+		large[i] = "Pardosa maesta"
+	}
+	largeDone := make(chan struct{})
+	go func() {
+		defer close(largeDone)
+		_, _ = m.MatchNamesDetailed(context.Background(), large)
+	}()
+
+	// wait until the large request is in the pool
+	for {
+		m.pool.mu.Lock()
+		started := len(m.pool.jobs) > 0
+		m.pool.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	res, err := m.MatchNamesDetailed(
+		context.Background(), []string{"Pardosa maesta", "Acacia may"},
+	)
+	assert.Nil(err)
+	assert.Equal(vlib.Fuzzy, res.Matches[0].MatchType)
+	select {
+	case <-largeDone:
+		t.Fatal("small request waited for the large one")
+	default:
+	}
+	<-largeDone
+}
+
+// TestPoolCanceled checks that names of a canceled request do not stay in
+// the pool.
+func TestPoolCanceled(t *testing.T) {
+	assert := assert.New(t)
+	m := mockMatcher()
+	m.pool = newPool(1, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	names := make([]string, 1_000)
+	for i := range names {
+		names[i] = "Bubo bubo"
+	}
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+	}()
+	res, err := m.MatchNamesDetailed(ctx, names)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal(len(names), len(res.Matches))
+	var errsNum int
+	for _, v := range res.Matches {
+		if v.Error != "" {
+			errsNum++
+		}
+	}
+	assert.Equal(errsNum, res.ErrorsNum)
+	assert.Greater(errsNum, 0)
+
+	m.pool.mu.Lock()
+	assert.Empty(m.pool.jobs)
+	m.pool.mu.Unlock()
+}
+
+// TestPoolClose checks that workers of a closed pool stop, and that the
+// pool does not accept new names.
+func TestPoolClose(t *testing.T) {
+	assert := assert.New(t)
+	m := mockMatcher()
+
+	res, err := m.MatchNamesDetailed(context.Background(), []string{"Bubo bubo"})
+	assert.Nil(err)
+	assert.Equal(1, len(res.Matches))
+
+	closed := make(chan struct{})
+	go func() {
+		m.pool.close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("workers of the closed pool did not stop")
+	}
+
+	j := m.pool.newJob(context.Background(), m, make(chan matchOut))
+	assert.False(m.pool.add(j, nameIn{name: "Bubo bubo"}))
+}
@@ -0,0 +1,203 @@
+package matcher
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/output"
+)
+
+// Weights of signals in the score of a match item. They add up to 1.
+const (
+	typeWeight        = 0.5
+	editWeight        = 0.2
+	cardinalityWeight = 0.15
+	dataSourcesWeight = 0.15
+)
+
+// matchTypeScores are the scores of match types of items that matched
+// the whole name. Scores of partial matches are multiplied by the
+// partial-match level.
+var matchTypeScores = map[vlib.MatchTypeValue]float64{
+	vlib.Exact:                    1,
+	vlib.Virus:                    1,
+	vlib.ExactSpeciesGroup:        0.95,
+	vlib.Fuzzy:                    0.9,
+	vlib.FuzzySpeciesGroup:        0.85,
+	vlib.FuzzyRelaxed:             0.8,
+	vlib.FuzzySpeciesGroupRelaxed: 0.75,
+	vlib.PartialExact:             0.7,
+	vlib.PartialFuzzy:             0.6,
+	vlib.PartialFuzzyRelaxed:      0.5,
+}
+
+// approxVirusScore is the match type score of virus names that were found
+// by fuzzy or token-set matching.
+const approxVirusScore = 0.8
+
+// score calculates the score of a match item in the range from 0 to 1.
+// The higher the score is, the more likely the item is the name that was
+// meant by the input. The score is a weighted sum of the following signals:
+//
+//   - match type (50%): 1 for exact matches, less for fuzzy, and even less
+//     for partial matches (see matchTypeScores). For partial matches the
+//     value is multiplied by the partial-match level, the share of words
+//     of the canonical form that were used for matching.
+//   - edit distance (20%): 1 / (1 + EditDistance + EditDistanceStem/2).
+//     For inputs with an abbreviated genus only edits of the input with
+//     the genus of the item are counted (see inferredEditDistance).
+//   - cardinality agreement (15%): the ratio of the smaller to the larger
+//     number of words in the canonical form and in the matched string.
+//     For partial matches the truncated canonical form is used, because
+//     removed words are already counted by the partial-match level.
+//   - number of data-sources (15%): 1 - 1/(1 + n), where n is the number
+//     of data-sources with the item.
+//
+// The score is rounded to 3 decimal places.
+func score(ns nameString, mi mlib.MatchItem) float64 {
+	canWords := len(strings.Fields(ns.Canonical))
+
+	typeScore := matchTypeScores[mi.MatchType]
+	if mi.MatchType == vlib.Virus && virusMatch(mi.InputStr, mi.MatchStr) != "" {
+		typeScore = approxVirusScore
+	}
+	if isPartial(mi.MatchType) && canWords > 0 {
+		level := float64(len(strings.Fields(mi.InputStr))) / float64(canWords)
+		typeScore *= min(level, 1)
+	}
+
+	editScore := 1 / (1 + float64(mi.EditDistance) +
+		float64(mi.EditDistanceStem)/2)
+	if genusInferred(mi.InputStr) {
+		ed := inferredEditDistance(mi.InputStr, mi.MatchStr)
+		editScore = 1 / (1 + float64(ed))
+	}
+
+	inWords := canWords
+	if isPartial(mi.MatchType) {
+		inWords = len(strings.Fields(mi.InputStr))
+	}
+	cardScore := 1.0
+	if inWords > 0 {
+		matchWords := len(strings.Fields(mi.MatchStr))
+		cardScore = float64(min(inWords, matchWords)) /
+			float64(max(inWords, matchWords))
+	}
+
+	dsNum := max(len(mi.DataSourcesMap), len(mi.DataSources))
+	dsScore := 1 - 1/(1+float64(dsNum))
+
+	res := typeWeight*typeScore + editWeight*editScore +
+		cardinalityWeight*cardScore + dataSourcesWeight*dsScore
+	return math.Round(res*1000) / 1000
+}
+
+func isPartial(mt vlib.MatchTypeValue) bool {
+	return mt == vlib.PartialExact ||
+		mt == vlib.PartialFuzzy ||
+		mt == vlib.PartialFuzzyRelaxed
+}
+
+// rankItems calculates scores of match items, sorts the items by their
+// scores, and keeps only the items allowed by BestItemsNum and MinScore
+// settings. It returns details of the remaining items in the same order.
+// If no items remain, the match gets NoMatch match type. In batch-context
+// mode the items are only scored, because their scores can change after
+// all names of the batch are matched.
+func (m matcher) rankItems(
+	ns nameString,
+	match *mlib.Match,
+) []output.ItemDetails {
+	details := scoreItems(ns, match)
+	if m.cfg.WithBatchContext {
+		return details
+	}
+	return m.selectItems(match, details)
+}
+
+// scoreItems calculates scores and other details of match items, and
+// sorts the items by their scores.
+func scoreItems(ns nameString, match *mlib.Match) []output.ItemDetails {
+	if len(match.MatchItems) == 0 {
+		return nil
+	}
+
+	res := make([]output.ItemDetails, len(match.MatchItems))
+	for i, v := range match.MatchItems {
+		res[i] = output.ItemDetails{
+			Score:         score(ns, v),
+			WordsChange:   wordsChange(v.InputStr, v.MatchStr),
+			GenusChange:   genusChange(v.InputStr, v.MatchStr),
+			GenusInferred: genusInferred(v.InputStr),
+		}
+		if v.MatchType == vlib.ExactSpeciesGroup {
+			res[i].SpeciesGroup = speciesGroupRelation(ns.Canonical, v.MatchStr)
+		}
+		if isPartial(v.MatchType) {
+			res[i].RemovedWords = removedWords(ns.Canonical, v.InputStr)
+		}
+		if v.MatchType == vlib.Virus {
+			res[i].VirusMatch = virusMatch(v.InputStr, v.MatchStr)
+		}
+	}
+	sortItems(match, res)
+	return res
+}
+
+// sortItems sorts match items and their details by scores from the
+// highest to the lowest. Items with the same score keep their order.
+func sortItems(match *mlib.Match, details []output.ItemDetails) {
+	type scored struct {
+		item    mlib.MatchItem
+		details output.ItemDetails
+	}
+	items := make([]scored, len(details))
+	for i := range details {
+		items[i] = scored{item: match.MatchItems[i], details: details[i]}
+	}
+	slices.SortStableFunc(items, func(a, b scored) int {
+		return cmp.Compare(b.details.Score, a.details.Score)
+	})
+	for i := range items {
+		match.MatchItems[i] = items[i].item
+		details[i] = items[i].details
+	}
+}
+
+// selectItems keeps only sorted items that are allowed by BestItemsNum and
+// MinScore settings, and returns their details. If no items remain, the
+// match gets NoMatch match type.
+func (m matcher) selectItems(
+	match *mlib.Match,
+	details []output.ItemDetails,
+) []output.ItemDetails {
+	if len(match.MatchItems) == 0 {
+		return nil
+	}
+
+	var items []mlib.MatchItem
+	var res []output.ItemDetails
+	for i := range details {
+		if details[i].Score < m.cfg.MinScore {
+			continue
+		}
+		items = append(items, match.MatchItems[i])
+		res = append(res, details[i])
+	}
+	if n := m.cfg.BestItemsNum; n > 0 && len(items) > n {
+		items = items[:n]
+		res = res[:n]
+	}
+
+	if len(items) == 0 {
+		match.MatchType = vlib.NoMatch
+		match.MatchItems = nil
+		return nil
+	}
+	match.MatchItems = items
+	return res
+}
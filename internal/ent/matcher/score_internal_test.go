@@ -0,0 +1,110 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/io/memio"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	assert := assert.New(t)
+	ns := nameString{Canonical: "Pardosa moesta"}
+	exact := mlib.MatchItem{
+		MatchStr:       "Pardosa moesta",
+		InputStr:       "Pardosa moesta",
+		MatchType:      vlib.Exact,
+		DataSourcesMap: map[int]struct{}{1: {}},
+	}
+	assert.Equal(0.925, score(ns, exact))
+
+	fuzzy := exact
+	fuzzy.MatchStr = "Pardosa moestus"
+	fuzzy.MatchType = vlib.Fuzzy
+	fuzzy.EditDistance = 2
+	assert.Less(score(ns, fuzzy), score(ns, exact))
+
+	ns = nameString{Canonical: "Pardosa moesta alba"}
+	partial := mlib.MatchItem{
+		MatchStr:  "Pardosa",
+		InputStr:  "Pardosa",
+		MatchType: vlib.PartialExact,
+	}
+	// 0.5*0.7/3 + 0.2 + 0.15 + 0
+	assert.Equal(0.467, score(ns, partial))
+
+	ns = nameString{Canonical: "Aus bus cus xus"}
+	partial = mlib.MatchItem{
+		MatchStr:  "Aus bus cus",
+		InputStr:  "Aus bus cus",
+		MatchType: vlib.PartialExact,
+	}
+	// 0.5*0.7*3/4 + 0.2 + 0.15 + 0
+	assert.Equal(0.612, score(ns, partial))
+}
+
+func TestRankItems(t *testing.T) {
+	assert := assert.New(t)
+	ns := nameString{Canonical: "Bubo bubo"}
+	newMatch := func() mlib.Match {
+		return mlib.Match{
+			MatchType: vlib.Fuzzy,
+			MatchItems: []mlib.MatchItem{
+				{ID: "1", MatchStr: "Bubo buba", MatchType: vlib.Fuzzy,
+					EditDistance: 1},
+				{ID: "2", MatchStr: "Bubo bubo", MatchType: vlib.Exact},
+				{ID: "3", MatchStr: "Bubo bubos", MatchType: vlib.Fuzzy,
+					EditDistance: 1, DataSourcesMap: map[int]struct{}{1: {}}},
+			},
+		}
+	}
+
+	m := matcher{cfg: config.New()}
+	match := newMatch()
+	details := m.rankItems(ns, &match)
+	assert.Equal(3, len(details))
+	ids := []string{match.MatchItems[0].ID, match.MatchItems[1].ID,
+		match.MatchItems[2].ID}
+	assert.Equal([]string{"2", "3", "1"}, ids)
+	assert.Greater(details[0].Score, details[1].Score)
+
+	m.cfg = config.New(config.OptBestItemsNum(1))
+	match = newMatch()
+	details = m.rankItems(ns, &match)
+	assert.Equal(1, len(details))
+	assert.Equal("2", match.MatchItems[0].ID)
+
+	m.cfg = config.New(config.OptMinScore(0.99))
+	match = newMatch()
+	details = m.rankItems(ns, &match)
+	assert.Nil(details)
+	assert.Equal(vlib.NoMatch, match.MatchType)
+	assert.Empty(match.MatchItems)
+}
+
+// TestMatchScores checks that match items are sorted by their scores, and
+// that BestItemsNum keeps only the best of them.
+func TestMatchScores(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, []memio.Name{
+		{Name: "Pardosa moesta", DataSources: []int{3}},
+		{Name: "Pardosa moestus", DataSources: []int{1, 3, 5}},
+	}, nil)
+
+	names := []string{"Pardosa moestus"}
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+	match := res.Matches[0]
+	assert.Equal(2, len(match.MatchItems))
+	assert.Equal(2, len(match.ItemsDetails))
+	assert.Equal("Pardosa moestus", match.MatchItems[0].MatchStr)
+	assert.Greater(match.ItemsDetails[0].Score, match.ItemsDetails[1].Score)
+
+	out := m.MatchNames(names, config.OptBestItemsNum(1))
+	assert.Equal(1, len(out.Matches[0].MatchItems))
+	assert.Equal("Pardosa moestus", out.Matches[0].MatchItems[0].MatchStr)
+}
@@ -0,0 +1,115 @@
+package matcher
+
+import (
+	"slices"
+	"strings"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/gnames/gnparser"
+	"github.com/gnames/gnparser/ent/stemmer"
+)
+
+// matchSpeciesGroup finds names of the species group of the name-string.
+// Binomials are expanded to autonyms, infraspecific names are collapsed to
+// their parent species. Infraspecific names also get sibling taxa of the
+// same species, that are found by the epithet index. All items have
+// ExactSpeciesGroup match type, and their relation to the input is given
+// by speciesGroupRelation.
+func (m matcher) matchSpeciesGroup(
+	parser gnparser.GNparser,
+	ns nameString,
+) (*mlib.Match, error) {
+	var matchItems []mlib.MatchItem
+	for _, v := range ns.spGroupStrings(parser, m.cfg.NomCode) {
+		res, err := m.matchStem(v, stageSpeciesGroup)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			matchItems = append(matchItems, res.MatchItems...)
+		}
+	}
+
+	if ns.Cardinality > 2 {
+		siblings, err := m.matchSiblings(ns)
+		if err != nil {
+			return nil, err
+		}
+		matchItems = append(matchItems, siblings...)
+	}
+
+	if len(matchItems) == 0 {
+		return nil, nil
+	}
+	for i := range matchItems {
+		matchItems[i].MatchType = vlib.ExactSpeciesGroup
+	}
+	return &mlib.Match{
+		ID:         ns.ID,
+		Name:       ns.Name,
+		MatchType:  vlib.ExactSpeciesGroup,
+		MatchItems: matchItems,
+	}, nil
+}
+
+// matchSiblings finds infraspecific names that belong to the same species
+// as the infraspecific name-string, for example 'Aus bus dus' and
+// 'Aus bus bus' for 'Aus bus cus'. Only stems that start with the species
+// of the name-string are read from the lookup data.
+func (m matcher) matchSiblings(
+	ns nameString,
+) (res []mlib.MatchItem, err error) {
+	st := m.trace.begin(stageSpeciesGroup, ns.CanonicalStem)
+	defer func() { st.end(&mlib.Match{MatchItems: res}) }()
+
+	words := strings.Split(ns.CanonicalStem, " ")
+	if len(words) < 3 {
+		return nil, nil
+	}
+	species := strings.Join(words[:2], " ")
+	items, err := m.fuzzyMatcher.SpeciesToMatchItems(species)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range items {
+		stem := stemmer.StemCanonical(v.MatchStr)
+		stemWords := strings.Split(stem, " ")
+		if len(stemWords) < 3 || stem == ns.CanonicalStem ||
+			!slices.Equal(stemWords[:2], words[:2]) {
+			continue
+		}
+		st.candidates([]string{stem})
+		v.InputStr = ns.Canonical
+		res = append(res, v)
+	}
+	return m.filterDataSources(res, st), nil
+}
+
+// speciesGroupRelation tells how a species group item is related to the
+// canonical form of the input. The item can be an autonym of a binomial
+// input, the parent species of an infraspecific input, or its sibling.
+func speciesGroupRelation(canonical, matchStr string) string {
+	words := strings.Split(stemmer.StemCanonical(canonical), " ")
+	matchWords := strings.Split(stemmer.StemCanonical(matchStr), " ")
+	if len(words) < 2 || len(matchWords) < 2 {
+		return ""
+	}
+	switch {
+	case len(matchWords) < len(words) &&
+		slices.Equal(matchWords, words[:len(matchWords)]):
+		return output.SpeciesGroupParent
+	case len(words) == 2 && len(matchWords) > 2 &&
+		slices.Equal(matchWords[:2], words) &&
+		!slices.ContainsFunc(matchWords[2:], func(w string) bool {
+			return w != words[1]
+		}):
+		return output.SpeciesGroupAutonym
+	case len(words) > 2 && len(matchWords) > 2 &&
+		slices.Equal(matchWords[:2], words[:2]):
+		return output.SpeciesGroupSibling
+	}
+	return ""
+}
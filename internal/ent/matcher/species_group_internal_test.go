@@ -0,0 +1,89 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpeciesGroupRelation(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		canonical, matchStr, res string
+	}{
+		{"Aus bus", "Aus bus bus", output.SpeciesGroupAutonym},
+		{"Aus bus", "Aus bus bus bus", output.SpeciesGroupAutonym},
+		{"Aus bus", "Aus bus cus", ""},
+		{"Aus bus cus", "Aus bus", output.SpeciesGroupParent},
+		{"Aus bus cus dus", "Aus bus cus", output.SpeciesGroupParent},
+		{"Aus bus cus", "Aus bus bus", output.SpeciesGroupSibling},
+		{"Aus bus cus", "Aus bus dus", output.SpeciesGroupSibling},
+		{"Aus bus cus", "Aus cus cus", ""},
+		{"Aus", "Aus bus", ""},
+	}
+	for _, v := range tests {
+		res := speciesGroupRelation(v.canonical, v.matchStr)
+		assert.Equal(v.res, res, v.canonical+" -> "+v.matchStr)
+	}
+}
+
+// TestSpeciesGroup checks autonyms, parent species and siblings found by
+// species group matching.
+func TestSpeciesGroup(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, memNames(
+		"Narcissus minor",
+		"Narcissus minor minor",
+		"Narcissus minor minor minor",
+		"Narcissus minor pumilus",
+		"Narcissus minor pumilus nanus",
+		"Narcissus minor valentinus",
+		"Narcissus major pumilus",
+	), nil)
+
+	names := []string{
+		"Narcissus minor L.",
+		"Narcissus minor var. pumilus",
+		"Narcissus minor subsp. pumilus var. nanus",
+	}
+	res, err := m.MatchNamesDetailed(context.Background(), names,
+		config.OptWithSpeciesGroup(true))
+	assert.Nil(err)
+
+	relations := func(match output.Match) map[string]string {
+		res := make(map[string]string)
+		for i, v := range match.MatchItems {
+			res[v.MatchStr] = match.ItemsDetails[i].SpeciesGroup
+		}
+		return res
+	}
+
+	match := res.Matches[0]
+	assert.Equal(vlib.Exact, match.MatchType)
+	assert.Equal(map[string]string{
+		"Narcissus minor":             "",
+		"Narcissus minor minor":       "autonym",
+		"Narcissus minor minor minor": "autonym",
+	}, relations(match))
+
+	match = res.Matches[1]
+	assert.Equal(vlib.Exact, match.MatchType)
+	assert.Equal(map[string]string{
+		"Narcissus minor pumilus":       "",
+		"Narcissus minor":               "parentSpecies",
+		"Narcissus minor minor":         "sibling",
+		"Narcissus minor minor minor":   "sibling",
+		"Narcissus minor pumilus nanus": "sibling",
+		"Narcissus minor valentinus":    "sibling",
+	}, relations(match))
+
+	match = res.Matches[2]
+	assert.Equal(vlib.Exact, match.MatchType)
+	assert.Equal("parentSpecies", relations(match)["Narcissus minor"])
+	assert.Equal("parentSpecies", relations(match)["Narcissus minor pumilus"])
+	assert.Equal("sibling", relations(match)["Narcissus minor valentinus"])
+}
@@ -0,0 +1,114 @@
+package matcher
+
+import (
+	"time"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnmatcher/pkg/output"
+)
+
+// Names of matching stages in explanations.
+const (
+	stageAbbreviation = "abbreviation"
+	stageAbbrBatch    = "abbreviationBatch"
+	stageAbbrEpithet  = "abbreviationEpithet"
+	stageExact        = "exactStem"
+	stageSpeciesGroup = "speciesGroup"
+	stageVirus        = "virus"
+	stageVirusFuzzy   = "virusFuzzy"
+	stageFuzzy        = "fuzzy"
+	stageWords        = "mergeSplit"
+	stageEpithet      = "epithet"
+	stagePartial      = "partial"
+	stagePartialGenus = "partialGenus"
+	stageHybrid       = "hybridFormula"
+)
+
+// Reasons of rejection of candidates that are not given by fuzzy package.
+const (
+	reasonDataSources = "not in requested data-sources"
+)
+
+// trace records how a name-string was matched, if explain mode is on.
+// Methods of nil trace and nil stageTrace do nothing, so matching stages
+// use them without checking if explain mode is on.
+type trace struct {
+	start  time.Time
+	expl   output.Explanation
+	stages []*stageTrace
+}
+
+// stageTrace records one attempted matching stage.
+type stageTrace struct {
+	start time.Time
+	stage output.Stage
+}
+
+func newTrace(ns nameString) *trace {
+	return &trace{
+		start: time.Now(),
+		expl: output.Explanation{
+			Canonical:     ns.Canonical,
+			CanonicalStem: ns.CanonicalStem,
+			Cardinality:   ns.Cardinality,
+			IsVirus:       ns.IsVirus,
+		},
+	}
+}
+
+// begin starts recording of a stage that uses the given input.
+func (t *trace) begin(name, input string) *stageTrace {
+	if t == nil {
+		return nil
+	}
+	st := &stageTrace{
+		start: time.Now(),
+		stage: output.Stage{Name: name, Input: input},
+	}
+	t.stages = append(t.stages, st)
+	return st
+}
+
+// explanation finishes the trace and returns its result.
+func (t *trace) explanation() *output.Explanation {
+	if t == nil {
+		return nil
+	}
+	res := t.expl
+	res.Stages = make([]output.Stage, len(t.stages))
+	for i := range t.stages {
+		res.Stages[i] = t.stages[i].stage
+	}
+	res.Duration = time.Since(t.start)
+	return &res
+}
+
+// candidates records stems that were found by the trie.
+func (st *stageTrace) candidates(stems []string) {
+	if st == nil {
+		return
+	}
+	st.stage.Candidates = append(st.stage.Candidates, stems...)
+}
+
+// reject records a candidate that was not accepted, and the reason.
+func (st *stageTrace) reject(candidate, reason string) {
+	if st == nil {
+		return
+	}
+	st.stage.Rejected = append(st.stage.Rejected, output.Rejection{
+		Candidate: candidate,
+		Reason:    reason,
+	})
+}
+
+// end finishes recording of the stage with its result.
+func (st *stageTrace) end(res *mlib.Match) {
+	if st == nil {
+		return
+	}
+	if res != nil {
+		st.stage.MatchItemsNum = len(res.MatchItems)
+	}
+	st.stage.Duration = time.Since(st.start)
+}
@@ -0,0 +1,48 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/io/memio"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExplain checks that explanations contain attempted stages with
+// their candidates and rejections.
+func TestExplain(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, []memio.Name{
+		{Name: "Bubo bubo", DataSources: []int{1}},
+		{Name: "Pardosa moesta", DataSources: []int{3}},
+	}, nil)
+
+	names := []string{"Pardosa maesta", "Bubo bubo"}
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+	assert.Nil(res.Matches[0].Explanation)
+
+	res, err = m.MatchNamesDetailed(context.Background(), names,
+		config.OptWithExplain(true), config.OptDataSources([]int{3}))
+	assert.Nil(err)
+	expl := res.Matches[0].Explanation
+	assert.NotNil(expl)
+	assert.Equal("Pardosa maesta", expl.Canonical)
+	assert.Equal("Pardosa maest", expl.CanonicalStem)
+	var stages []string
+	for _, v := range expl.Stages {
+		stages = append(stages, v.Name)
+	}
+	assert.Equal([]string{"abbreviation", "exactStem", "fuzzy"}, stages)
+	assert.Equal([]string{"Pardosa moest"}, expl.Stages[2].Candidates)
+	assert.Equal(1, expl.Stages[2].MatchItemsNum)
+
+	expl = res.Matches[1].Explanation
+	assert.Equal(vlib.NoMatch, res.Matches[1].MatchType)
+	rejected := expl.Stages[1].Rejected
+	assert.Equal(1, len(rejected))
+	assert.Equal("Bubo bubo", rejected[0].Candidate)
+	assert.Equal("not in requested data-sources", rejected[0].Reason)
+}
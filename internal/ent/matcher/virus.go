@@ -0,0 +1,46 @@
+package matcher
+
+import (
+	"strings"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/ent/virus"
+	"github.com/gnames/gnmatcher/pkg/output"
+)
+
+// matchVirusFuzzy finds virus names that match the input approximately:
+// with a few edits in tokens, with tokens in a different order, or with
+// extra tokens, like a strain. It is used if no virus names start with the
+// input.
+func (m matcher) matchVirusFuzzy(ns nameString) (res []mlib.MatchItem, err error) {
+	st := m.trace.begin(stageVirusFuzzy, ns.Name)
+	defer func() { st.end(&mlib.Match{MatchItems: res}) }()
+
+	res, err = m.virusMatcher.MatchVirusFuzzy(ns.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range res {
+		st.candidates([]string{v.MatchStr})
+	}
+	return res, nil
+}
+
+// virusMatch tells how a virus name was found by the input. It returns an
+// empty string if the virus name starts with the input, otherwise the name
+// was found by fuzzy or token-set matching.
+func virusMatch(input, matchStr string) string {
+	in := strings.Join(virus.Tokens(input), " ")
+	if strings.HasPrefix(strings.Join(virus.Tokens(matchStr), " "), in) {
+		return ""
+	}
+	approx, ok := virus.Approximate(input, matchStr)
+	switch {
+	case !ok:
+		return ""
+	case approx.TokenSet:
+		return output.VirusTokenSet
+	default:
+		return output.VirusFuzzy
+	}
+}
@@ -0,0 +1,59 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestApproximateVirus checks fuzzy and token-set matching of viruses.
+func TestApproximateVirus(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, nil, memNames(
+		"Tobacco mosaic virus",
+		"Tobacco necrosis virus",
+		"Cucumber mosaic virus strain Y",
+	))
+
+	names := []string{
+		"Tobacco mosaic virus",
+		"Tobaco mosaic virus",
+		"mosaic virus tobacco",
+		"Cucumber mosaic virus Y",
+		"Potato virus",
+	}
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+
+	match := res.Matches[0]
+	assert.Equal(vlib.Virus, match.MatchType)
+	assert.Equal("", match.ExtendedMatchType)
+	assert.Equal("", match.ItemsDetails[0].VirusMatch)
+	assert.Equal(0, match.MatchItems[0].EditDistance)
+
+	match = res.Matches[1]
+	assert.Equal(vlib.Virus, match.MatchType)
+	assert.Equal(output.ApproximateVirus, match.ExtendedMatchType)
+	assert.Equal(1, len(match.MatchItems))
+	assert.Equal("Tobacco mosaic virus", match.MatchItems[0].MatchStr)
+	assert.Equal(1, match.MatchItems[0].EditDistance)
+	assert.Equal(output.VirusFuzzy, match.ItemsDetails[0].VirusMatch)
+	assert.Less(match.ItemsDetails[0].Score, res.Matches[0].ItemsDetails[0].Score)
+
+	match = res.Matches[2]
+	assert.Equal(vlib.Virus, match.MatchType)
+	assert.Equal(output.ApproximateVirus, match.ExtendedMatchType)
+	assert.Equal("Tobacco mosaic virus", match.MatchItems[0].MatchStr)
+	assert.Equal(output.VirusTokenSet, match.ItemsDetails[0].VirusMatch)
+	assert.Equal(1, match.MatchItems[0].EditDistance)
+
+	match = res.Matches[3]
+	assert.Equal(vlib.Virus, match.MatchType)
+	assert.Equal("Cucumber mosaic virus strain Y", match.MatchItems[0].MatchStr)
+	assert.Equal(output.VirusTokenSet, match.ItemsDetails[0].VirusMatch)
+
+	assert.Equal(vlib.NoMatch, res.Matches[4].MatchType)
+}
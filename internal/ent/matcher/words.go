@@ -0,0 +1,115 @@
+package matcher
+
+import (
+	"strings"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/gnames/gnparser/ent/stemmer"
+	"github.com/gnames/gnuuid"
+)
+
+// minSplitPart is the minimal number of characters in each part of a split
+// word.
+const minSplitPart = 3
+
+// matchWords tries to match versions of the canonical form where two
+// adjacent words are merged, or a word is split in two. It finds names
+// like 'Pomatomus saltatrix' written as 'Pomato mus saltatrix', that cannot
+// be found by fuzzy matching, because the trie and edit distance checks
+// assume the same number of words. Versions are checked against the bloom
+// filter and the trie of stems.
+func (m matcher) matchWords(ns nameString) (res *mlib.Match, err error) {
+	st := m.trace.begin(stageWords, ns.Canonical)
+	defer func() { st.end(res) }()
+
+	matchType := vlib.Fuzzy
+	if m.cfg.WithRelaxedFuzzyMatch {
+		matchType = vlib.FuzzyRelaxed
+	}
+
+	var matchItems []mlib.MatchItem
+	for _, canonical := range wordsVariants(ns.Canonical) {
+		stem := stemmer.StemCanonical(canonical)
+		stemID := gnuuid.New(stem).String()
+		items, err := m.exactStemMatches(stemID, stem)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			continue
+		}
+		st.candidates([]string{stem})
+
+		editDistanceStem, _ := m.editDistance(ns.CanonicalStem, stem)
+		for _, v := range items {
+			editDistance, reason := m.editDistance(ns.Canonical, v.MatchStr)
+			if editDistance == -1 {
+				st.reject(v.MatchStr, reason)
+				continue
+			}
+			v.InputStr = ns.Canonical
+			v.EditDistance = editDistance
+			v.EditDistanceStem = editDistanceStem
+			v.MatchType = matchType
+			matchItems = append(matchItems, v)
+		}
+	}
+
+	matchItems = m.filterDataSources(matchItems, st)
+	if len(matchItems) == 0 {
+		return nil, nil
+	}
+
+	res = &mlib.Match{
+		ID:         ns.ID,
+		Name:       ns.Name,
+		MatchType:  matchType,
+		MatchItems: matchItems,
+	}
+	return res, nil
+}
+
+// wordsVariants returns versions of a canonical form with two adjacent
+// words merged, and versions with one of the words split in two parts.
+func wordsVariants(canonical string) []string {
+	words := strings.Split(canonical, " ")
+	var res []string
+	for i := 1; i < len(words); i++ {
+		merged := make([]string, 0, len(words)-1)
+		merged = append(merged, words[:i-1]...)
+		merged = append(merged, words[i-1]+words[i])
+		merged = append(merged, words[i+1:]...)
+		res = append(res, strings.Join(merged, " "))
+	}
+
+	for i, w := range words {
+		rs := []rune(w)
+		for j := minSplitPart; j <= len(rs)-minSplitPart; j++ {
+			split := make([]string, 0, len(words)+1)
+			split = append(split, words[:i]...)
+			split = append(split, string(rs[:j]), string(rs[j:]))
+			split = append(split, words[i+1:]...)
+			res = append(res, strings.Join(split, " "))
+		}
+	}
+	return res
+}
+
+// wordsChange tells if a match item was found by merging or splitting
+// words of the input. Such items have a different number of words in the
+// input and the matched string. Fuzzy matching finds some of them as well,
+// if the change costs one edit. Partial matches change the input, so they
+// keep the same number of words.
+func wordsChange(input, matchStr string) string {
+	inputNum := strings.Count(input, " ")
+	matchNum := strings.Count(matchStr, " ")
+	switch {
+	case matchNum < inputNum:
+		return output.WordsMerged
+	case matchNum > inputNum:
+		return output.WordsSplit
+	}
+	return ""
+}
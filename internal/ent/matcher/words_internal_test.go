@@ -0,0 +1,77 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordsVariants(t *testing.T) {
+	assert := assert.New(t)
+	res := wordsVariants("Pomato mus saltatrix")
+	assert.Contains(res, "Pomatomus saltatrix")
+	assert.Contains(res, "Pomato mussaltatrix")
+	assert.Contains(res, "Pomato mus sal tatrix")
+	assert.NotContains(res, "Pomato mus sa ltatrix")
+
+	res = wordsVariants("Aus buscus")
+	assert.Equal([]string{"Ausbuscus", "Aus bus cus"}, res)
+}
+
+func TestWordsChange(t *testing.T) {
+	testData := []struct {
+		canonical, input, res string
+	}{
+		{"Aus bus", "Aus bus", ""},
+		{"Aus bus", "Aus bas", ""},
+		{"Pomato mus saltatrix", "Pomatomus saltatrix", output.WordsMerged},
+		{"Aus buscus", "Aus bus cus", output.WordsSplit},
+	}
+	for _, v := range testData {
+		assert.Equal(t, v.res, wordsChange(v.canonical, v.input), v.input)
+	}
+}
+
+// TestMergeSplitWords checks matching of names with merged or split
+// words.
+func TestMergeSplitWords(t *testing.T) {
+	assert := assert.New(t)
+	m := memMatcher(t, memNames(
+		"Pomatomus saltatrix", "Aus bus cus", "Aus", "Homo sapiens",
+	), nil)
+
+	names := []string{"Pomato mus saltatrix", "Aus buscus", "Homosapiens"}
+	res, err := m.MatchNamesDetailed(context.Background(), names)
+	assert.Nil(err)
+
+	// words are not merged or split by default
+	assert.Equal(vlib.PartialExact, res.Matches[1].MatchType)
+	assert.Equal("Aus", res.Matches[1].MatchItems[0].MatchStr)
+	assert.Equal(vlib.NoMatch, res.Matches[2].MatchType)
+
+	res, err = m.MatchNamesDetailed(
+		context.Background(), names, config.OptWithWordsChange(true),
+	)
+	assert.Nil(err)
+
+	match := res.Matches[0]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal("Pomatomus saltatrix", match.MatchItems[0].MatchStr)
+	assert.Equal("Pomato mus saltatrix", match.MatchItems[0].InputStr)
+	assert.Equal(1, match.MatchItems[0].EditDistance)
+	assert.Equal(output.WordsMerged, match.ItemsDetails[0].WordsChange)
+
+	match = res.Matches[1]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal("Aus bus cus", match.MatchItems[0].MatchStr)
+	assert.Equal(output.WordsSplit, match.ItemsDetails[0].WordsChange)
+
+	match = res.Matches[2]
+	assert.Equal(vlib.Fuzzy, match.MatchType)
+	assert.Equal("Homo sapiens", match.MatchItems[0].MatchStr)
+	assert.Equal(output.WordsSplit, match.ItemsDetails[0].WordsChange)
+}
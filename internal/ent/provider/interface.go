@@ -0,0 +1,66 @@
+// package provider contains an interface to the sources of data that are
+// used for building lookup caches of gnmatcher.
+package provider
+
+// DataProvider gives access to data needed for building bloom filters,
+// trie of stems, stems key-value store and viruses lookup data. Data can
+// come from gnames PostgreSQL database, local files, or any other source.
+//
+// Methods that iterate over records call the given function for every
+// record. If the function returns an error, iteration stops and the error
+// is returned.
+type DataProvider interface {
+	// StemsNum returns the number of stemmed canonical forms.
+	StemsNum() (int, error)
+
+	// Stems iterates over stemmed canonical forms sorted by their names.
+	Stems(fn func(Stem) error) error
+
+	// StemCanonicals iterates over combinations of stems, canonical forms
+	// and data-sources that contain these canonical forms. Records are
+	// sorted by stem, records of the same canonical form go one after
+	// another.
+	StemCanonicals(fn func(StemCanonical) error) error
+
+	// Viruses iterates over names of viruses and data-sources that contain
+	// them. Records from curated data-sources go first, records of the same
+	// virus go one after another.
+	Viruses(fn func(Virus) error) error
+}
+
+// Stem is a stemmed canonical form.
+type Stem struct {
+	// ID is UUIDv5 generated from the stem.
+	ID string
+
+	// Name is the stemmed canonical form.
+	Name string
+}
+
+// StemCanonical connects a stem, a canonical form that corresponds to
+// the stem, and a data-source where the canonical form is found.
+type StemCanonical struct {
+	// Stem is a stemmed canonical form.
+	Stem string
+
+	// CanonicalID is UUIDv5 generated from the canonical form.
+	CanonicalID string
+
+	// Canonical is the canonical form.
+	Canonical string
+
+	// DataSourceID is the ID of a data-source that has the canonical form.
+	DataSourceID int
+}
+
+// Virus is a name of a virus, plasmid, prion etc. found in a data-source.
+type Virus struct {
+	// ID is UUIDv5 generated from the name-string.
+	ID string
+
+	// Name is the name-string of the virus.
+	Name string
+
+	// DataSourceID is the ID of a data-source that has the name.
+	DataSourceID int
+}
@@ -0,0 +1,145 @@
+package virus
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/levenshtein/ent/editdist"
+)
+
+// Limit is the maximal number of virus names returned for one input.
+const Limit = 21
+
+// Approx describes how a virus name approximately matches the input.
+type Approx struct {
+	// EditDistance is the sum of edit distances between matched tokens.
+	EditDistance int
+
+	// TokenSet is true if tokens were matched in a different order, or the
+	// virus name has tokens that are absent in the input.
+	TokenSet bool
+
+	// ExtraTokens is the number of tokens of the virus name that are absent
+	// in the input.
+	ExtraTokens int
+}
+
+// Candidate is a virus name that approximately matches the input.
+type Candidate struct {
+	Item mlib.MatchItem
+	Approx
+}
+
+// Tokens splits a virus name into lowercase tokens that consist of letters
+// and digits.
+func Tokens(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Approximate compares the input with a virus name. Tokens of the input
+// are matched to tokens of the virus name in any order, allowing a few
+// edits in long tokens, and the virus name can have some extra tokens, like
+// a strain. If tokens do not match, for example because a strain is
+// written as 'U-1' instead of 'U1', the names are compared without
+// separators, and they must be the same. It returns false if the names do
+// not match.
+func Approximate(input, name string) (Approx, bool) {
+	inTokens, nameTokens := Tokens(input), Tokens(name)
+	if len(inTokens) == 0 || len(nameTokens) == 0 {
+		return Approx{}, false
+	}
+	if res, ok := matchTokens(inTokens, nameTokens); ok {
+		return res, true
+	}
+
+	if strings.Join(inTokens, "") == strings.Join(nameTokens, "") {
+		return Approx{}, true
+	}
+	return Approx{}, false
+}
+
+// matchTokens finds the closest unused token of the virus name for every
+// token of the input.
+func matchTokens(inTokens, nameTokens []string) (Approx, bool) {
+	extra := len(nameTokens) - len(inTokens)
+	if extra < 0 || extra >= len(inTokens) {
+		return Approx{}, false
+	}
+
+	var res Approx
+	used := make([]bool, len(nameTokens))
+	last := -1
+	for _, t := range inTokens {
+		best, bestED := -1, maxTokenEdits(t)+1
+		for i, nt := range nameTokens {
+			if used[i] {
+				continue
+			}
+			ed, _, _ := editdist.ComputeDistance(t, nt, false)
+			if ed < bestED {
+				best, bestED = i, ed
+			}
+		}
+		if best == -1 {
+			return Approx{}, false
+		}
+		used[best] = true
+		res.EditDistance += bestED
+		if best < last {
+			res.TokenSet = true
+		}
+		last = best
+	}
+	res.ExtraTokens = extra
+	if extra > 0 {
+		res.TokenSet = true
+	}
+	return res, true
+}
+
+// maxTokenEdits is the maximal edit distance allowed for a token.
+func maxTokenEdits(token string) int {
+	l := len([]rune(token))
+	switch {
+	case l < 5:
+		return 0
+	case l < 10:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Select ranks candidates and returns the best of them as match items.
+// Candidates with tokens in the same order go first, then candidates with
+// smaller edit distance, and with fewer extra tokens. EditDistance of the
+// items is the edit distance of their tokens, but not less than 1, so
+// approximate matches differ from exact ones that have 0.
+func Select(cands []Candidate) []mlib.MatchItem {
+	slices.SortStableFunc(cands, func(a, b Candidate) int {
+		if a.TokenSet != b.TokenSet {
+			if a.TokenSet {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Or(
+			cmp.Compare(a.EditDistance, b.EditDistance),
+			cmp.Compare(a.ExtraTokens, b.ExtraTokens),
+		)
+	})
+	if len(cands) > Limit {
+		cands = cands[:Limit]
+	}
+	res := make([]mlib.MatchItem, len(cands))
+	for i, v := range cands {
+		res[i] = v.Item
+		res[i].EditDistance = max(v.EditDistance, 1)
+	}
+	return res
+}
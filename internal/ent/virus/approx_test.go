@@ -0,0 +1,66 @@
+package virus_test
+
+import (
+	"fmt"
+	"testing"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/ent/virus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApproximate(t *testing.T) {
+	testData := []struct {
+		input, name string
+		ok          bool
+		approx      virus.Approx
+	}{
+		{"Tobacco mosaic virus", "Tobacco mosaic virus", true, virus.Approx{}},
+		{"Tobaco mosaic virus", "Tobacco mosaic virus", true,
+			virus.Approx{EditDistance: 1}},
+		{"mosaic virus tobacco", "Tobacco mosaic virus", true,
+			virus.Approx{TokenSet: true}},
+		{"Cucumber mosaic virus Y", "Cucumber mosaic virus strain Y", true,
+			virus.Approx{TokenSet: true, ExtraTokens: 1}},
+		{"Tobacco virus U-1", "Tobacco virus U1", true,
+			virus.Approx{}},
+		// short tokens must be the same
+		{"Tobacco mosaic virus U2", "Tobacco mosaic virus U1", false,
+			virus.Approx{}},
+		// too many extra tokens
+		{"virus", "Tobacco mosaic virus", false, virus.Approx{}},
+		{"Potato virus", "Tobacco necrosis virus", false, virus.Approx{}},
+	}
+
+	for _, v := range testData {
+		msg := fmt.Sprintf("'%s' vs '%s'", v.input, v.name)
+		res, ok := virus.Approximate(v.input, v.name)
+		assert.Equal(t, v.ok, ok, msg)
+		assert.Equal(t, v.approx, res, msg)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	assert := assert.New(t)
+	cands := []virus.Candidate{
+		{
+			Item:   mlib.MatchItem{MatchStr: "Tobacco mosaic virus strain U1"},
+			Approx: virus.Approx{TokenSet: true, ExtraTokens: 2},
+		},
+		{
+			Item:   mlib.MatchItem{MatchStr: "Tobacco mosaic virus"},
+			Approx: virus.Approx{EditDistance: 1},
+		},
+		{
+			Item:   mlib.MatchItem{MatchStr: "Tobacco mosaic virus U1"},
+			Approx: virus.Approx{TokenSet: true, ExtraTokens: 1},
+		},
+	}
+	res := virus.Select(cands)
+	assert.Equal(3, len(res))
+	assert.Equal("Tobacco mosaic virus", res[0].MatchStr)
+	assert.Equal(1, res[0].EditDistance)
+	assert.Equal("Tobacco mosaic virus U1", res[1].MatchStr)
+	assert.Equal(1, res[1].EditDistance)
+	assert.Equal("Tobacco mosaic virus strain U1", res[2].MatchStr)
+}
@@ -4,7 +4,6 @@ package virus
 
 import (
 	mlib "github.com/gnames/gnlib/ent/matcher"
-	"github.com/gnames/gnmatcher/pkg/config"
 )
 
 type VirusMatcher interface {
@@ -12,9 +11,6 @@ type VirusMatcher interface {
 	// exist yet.
 	Init() error
 
-	// SetConfig updates configuration of the matcher.
-	SetConfig(cfg config.Config)
-
 	// MatchVirus takes a virus name and returns back matched items for
 	// the name. In case if there were too many returned results, returns an
 	// error. Matching is successful if entered name matches the start of the
@@ -23,6 +19,11 @@ type VirusMatcher interface {
 	// returned results.
 	MatchVirus(s string) ([]mlib.MatchItem, error)
 
+	// MatchVirusFuzzy takes a virus name and returns virus names that match
+	// it approximately (see Approximate function). It is used if MatchVirus
+	// did not find anything. Results are ranked by Select function.
+	MatchVirusFuzzy(s string) ([]mlib.MatchItem, error)
+
 	// NameToBytes normalizes a virus name by removing all extra spaces,
 	// converting all runes to lower case, adding '\x00' to the start and
 	// returning result as bytes.
@@ -6,23 +6,59 @@ package bloom
 
 import (
 	"log/slog"
+	"path/filepath"
 
 	"github.com/gnames/gnmatcher/internal/ent/exact"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
 	"github.com/gnames/gnmatcher/pkg/config"
 	"github.com/gnames/gnsys"
+	"github.com/gnames/gnuuid"
 )
 
 type exactMatcher struct {
 	cfg     config.Config
+	data    provider.DataProvider
 	filters *bloomFilters
 }
 
-// New takes configuration object and returns ExactMatcher.
-func New(cfg config.Config) exact.ExactMatcher {
-	em := &exactMatcher{cfg: cfg}
+// New takes configuration object and a provider of lookup data, and returns
+// ExactMatcher. The data are used only if the cache of filters is empty.
+func New(cfg config.Config, data provider.DataProvider) exact.ExactMatcher {
+	em := &exactMatcher{cfg: cfg, data: data}
 	return em
 }
 
+// Build creates bloom filters from the data provider and saves them to
+// the cache, replacing filters that might be there already. It returns
+// the number of stems added to the filters.
+func Build(cfg config.Config, data provider.DataProvider) (int, error) {
+	em := &exactMatcher{cfg: cfg, data: data}
+	err := em.prepareDir()
+	if err != nil {
+		return 0, err
+	}
+	err = em.filtersFromData(cfg.FiltersDir())
+	if err != nil {
+		return 0, err
+	}
+	return int(em.filters.canonicalSize), nil
+}
+
+// Check loads bloom filters from the cache to make sure they are complete.
+// It returns the number of stems in the filters.
+func Check(cfg config.Config) (int, error) {
+	em := &exactMatcher{cfg: cfg}
+	path := cfg.FiltersDir()
+	err := em.getFiltersFromCache(
+		filepath.Join(path, canonicalStemFile),
+		filepath.Join(path, sizesFile),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(em.filters.canonicalSize), nil
+}
+
 func (em *exactMatcher) Init() error {
 	err := em.prepareDir()
 	if err != nil {
@@ -37,11 +73,6 @@ func (em *exactMatcher) Init() error {
 	return nil
 }
 
-// SetConfig updates configuration of the matcher.
-func (em *exactMatcher) SetConfig(cfg config.Config) {
-	em.cfg = cfg
-}
-
 func (em *exactMatcher) MatchCanonicalID(uuid string) bool {
 	em.filters.mux.Lock()
 	isIn := em.filters.canonicalStem.Check([]byte(uuid))
@@ -49,6 +80,22 @@ func (em *exactMatcher) MatchCanonicalID(uuid string) bool {
 	return isIn
 }
 
+// AddStems adds IDs of stems to the filter. The size of the filter does
+// not change, so the rate of false positives grows with every added stem,
+// until the filter is rebuilt.
+func (em *exactMatcher) AddStems(data provider.DataProvider) error {
+	em.filters.mux.Lock()
+	err := data.StemCanonicals(func(sc provider.StemCanonical) error {
+		em.filters.canonicalStem.Add([]byte(gnuuid.New(sc.Stem).String()))
+		return nil
+	})
+	em.filters.mux.Unlock()
+	if err != nil {
+		return err
+	}
+	return saveFilters(em.cfg.FiltersDir(), em.filters)
+}
+
 func (em exactMatcher) prepareDir() error {
 	slog.Info("Preparing dir for bloom filters")
 	bloomDir := em.cfg.FiltersDir()
@@ -49,6 +49,7 @@ func (em *exactMatcher) getFiltersFromCache(cPath, sizesPath string) error {
 
 	em.filters = &bloomFilters{
 		canonicalStem: cFilter,
+		canonicalSize: cCfg.N,
 	}
 	return nil
 }
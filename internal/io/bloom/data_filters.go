@@ -0,0 +1,61 @@
+package bloom
+
+import (
+	"log/slog"
+
+	"github.com/devopsfaith/bloomfilter"
+	baseBloomfilter "github.com/devopsfaith/bloomfilter/bloomfilter"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/io/progress"
+)
+
+// filtersFromData creates filters using data from the DataProvider and
+// saves them to the cache.
+func (em *exactMatcher) filtersFromData(path string) error {
+	slog.Info("Importing lookup data for stemmed canonicals")
+	cFilter, cSize, err := createFilter(em.data)
+	if err != nil {
+		return err
+	}
+	em.filters = &bloomFilters{
+		canonicalStem: cFilter,
+		canonicalSize: cSize,
+	}
+	return saveFilters(path, em.filters)
+}
+
+func createFilter(
+	data provider.DataProvider,
+) (*baseBloomfilter.Bloomfilter, uint, error) {
+	var nilFilter *baseBloomfilter.Bloomfilter
+
+	size, err := data.StemsNum()
+	if err != nil {
+		return nilFilter, 0, err
+	}
+	return newFilter(data, uint(size))
+}
+
+func newFilter(
+	data provider.DataProvider,
+	filterSize uint,
+) (*baseBloomfilter.Bloomfilter, uint, error) {
+	cfg := bloomfilter.Config{
+		N:        filterSize,
+		P:        0.00001,
+		HashName: bloomfilter.HASHER_OPTIMAL,
+	}
+	bf := baseBloomfilter.New(cfg)
+
+	p := progress.New("bloom", int(filterSize))
+	err := data.Stems(func(stem provider.Stem) error {
+		bf.Add([]byte(stem.ID))
+		p.Inc()
+		return nil
+	})
+	if err != nil {
+		return bf, filterSize, err
+	}
+	p.Finish()
+	return bf, filterSize, nil
+}
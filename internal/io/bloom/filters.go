@@ -33,7 +33,8 @@ type bloomFilters struct {
 
 // getFilters returns bloom filters for name-string matching.
 // If filters had been already created before, it just returns them.
-// Otherwise it creates filters from either database, or from cached files.
+// Otherwise it creates filters from either cached files, or from the data
+// provider.
 // Creating filters from cache is significantly faster.
 func (em *exactMatcher) getFilters() error {
 	path := em.cfg.FiltersDir()
@@ -53,10 +54,10 @@ func (em *exactMatcher) getFilters() error {
 		return nil
 	}
 
-	err = em.filtersFromDB(path)
+	err = em.filtersFromData(path)
 	if err != nil {
 		slog.Error(
-			"Cannot create filters from database",
+			"Cannot create filters from data provider",
 			"path", path,
 			"error", err,
 		)
@@ -9,6 +9,8 @@ import (
 	baseBloomfilter "github.com/devopsfaith/bloomfilter/bloomfilter"
 )
 
+// saveFilters writes filters to temporary files and renames them, so
+// an interrupted save does not leave truncated filters in the cache.
 func saveFilters(path string, filters *bloomFilters) error {
 	var err error
 	var nilFilter *baseBloomfilter.Bloomfilter
@@ -20,26 +22,30 @@ func saveFilters(path string, filters *bloomFilters) error {
 	for f, filter := range files {
 		var file *os.File
 		filePath := filepath.Join(path, f)
+		tmpPath := filePath + ".tmp"
 
-		file, err = createFile(filePath)
+		file, err = createFile(tmpPath)
 		if err != nil {
-			slog.Error("Cannot create path", "path", filePath, "error", err)
+			slog.Error("Cannot create path", "path", tmpPath, "error", err)
 			return err
 		}
 		if f == sizesFile {
 			err = saveSizesFile(file, filters)
-			if err != nil {
-				slog.Error("Cannot create sizesFile", "error", err)
-				return err
-			}
-			continue
+		} else {
+			err = saveFilterFile(filePath, file, filter)
+		}
+		if cerr := file.Close(); err == nil {
+			err = cerr
 		}
-
-		err = saveFilterFile(filePath, file, filter)
 		if err != nil {
+			os.Remove(tmpPath)
 			slog.Error("Cannot create file", "file", filePath, "error", err)
 			return err
 		}
+		if err = os.Rename(tmpPath, filePath); err != nil {
+			slog.Error("Cannot replace file", "file", filePath, "error", err)
+			return err
+		}
 	}
 
 	slog.Info("Saved cached filters to disk")
@@ -0,0 +1,237 @@
+// package dumpio implements DataProvider interface using a local dump of
+// gnames data saved as tab-separated files. It allows to build lookup
+// caches without access to gnames PostgreSQL database, for example on an
+// air-gapped machine, or for tests.
+//
+// The dump directory has to contain the following files. The first line of
+// every file is a header with names of the columns, the order of columns
+// does not matter. If an `id` field is empty, it is generated as UUIDv5 from
+// the corresponding name.
+//
+//	canonical_stems.tsv
+//	  id    UUIDv5 of the stemmed canonical form
+//	  name  stemmed canonical form, for example "Pomatom saltatr"
+//
+//	canonicals.tsv
+//	  id    UUIDv5 of the canonical form
+//	  name  canonical form, for example "Pomatomus saltatrix"
+//	  stem  stemmed canonical form, for example "Pomatom saltatr"
+//
+//	name_string_indices.tsv
+//	  canonical_id    UUIDv5 of the canonical form
+//	  data_source_id  ID of a data-source that contains the canonical form
+//
+//	viruses.tsv
+//	  id              UUIDv5 of the virus name-string
+//	  name            name-string of the virus
+//	  data_source_id  ID of a data-source that contains the name
+//
+// Viruses are given a priority in the order they appear in the file, so
+// names from curated data-sources should go first.
+//
+// Incremental updates of the cache take a delta in the same format. The
+// delta needs only canonicals.tsv and name_string_indices.tsv files with
+// new canonical forms and their data-sources.
+package dumpio
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnuuid"
+)
+
+// Names of the files of a dump.
+const (
+	StemsFile      = "canonical_stems.tsv"
+	CanonicalsFile = "canonicals.tsv"
+	IndicesFile    = "name_string_indices.tsv"
+	VirusesFile    = "viruses.tsv"
+)
+
+type dumpio struct {
+	dir string
+}
+
+// New creates DataProvider that reads data from files located in the
+// given directory.
+func New(dir string) provider.DataProvider {
+	return dumpio{dir: dir}
+}
+
+func (d dumpio) StemsNum() (int, error) {
+	stems, err := d.stems()
+	if err != nil {
+		return 0, err
+	}
+	return len(stems), nil
+}
+
+func (d dumpio) Stems(fn func(provider.Stem) error) error {
+	stems, err := d.stems()
+	if err != nil {
+		return err
+	}
+	for _, v := range stems {
+		if err = fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d dumpio) StemCanonicals(fn func(provider.StemCanonical) error) error {
+	type canonical struct {
+		id, name, stem string
+	}
+	var cans []canonical
+	fields := []string{"id", "name", "stem"}
+	err := d.readTSV(CanonicalsFile, fields, func(row []string) error {
+		id := row[0]
+		if id == "" {
+			id = gnuuid.New(row[1]).String()
+		}
+		cans = append(cans, canonical{id: id, name: row[1], stem: row[2]})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	dss := make(map[string][]int)
+	fields = []string{"canonical_id", "data_source_id"}
+	err = d.readTSV(IndicesFile, fields, func(row []string) error {
+		dsID, err := strconv.Atoi(row[1])
+		if err != nil {
+			return fmt.Errorf("bad data_source_id '%s': %w", row[1], err)
+		}
+		if !slices.Contains(dss[row[0]], dsID) {
+			dss[row[0]] = append(dss[row[0]], dsID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(cans, func(a, b canonical) int {
+		if res := strings.Compare(a.stem, b.stem); res != 0 {
+			return res
+		}
+		return strings.Compare(a.id, b.id)
+	})
+
+	for _, c := range cans {
+		ids := dss[c.id]
+		slices.Sort(ids)
+		for _, dsID := range ids {
+			sc := provider.StemCanonical{
+				Stem:         c.stem,
+				CanonicalID:  c.id,
+				Canonical:    c.name,
+				DataSourceID: dsID,
+			}
+			if err = fn(sc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d dumpio) Viruses(fn func(provider.Virus) error) error {
+	fields := []string{"id", "name", "data_source_id"}
+	return d.readTSV(VirusesFile, fields, func(row []string) error {
+		dsID, err := strconv.Atoi(row[2])
+		if err != nil {
+			return fmt.Errorf("bad data_source_id '%s': %w", row[2], err)
+		}
+		id := row[0]
+		if id == "" {
+			id = gnuuid.New(row[1]).String()
+		}
+		return fn(provider.Virus{ID: id, Name: row[1], DataSourceID: dsID})
+	})
+}
+
+// stems reads all stems from the dump and sorts them by name.
+func (d dumpio) stems() ([]provider.Stem, error) {
+	var res []provider.Stem
+	fields := []string{"id", "name"}
+	err := d.readTSV(StemsFile, fields, func(row []string) error {
+		id := row[0]
+		if id == "" {
+			id = gnuuid.New(row[1]).String()
+		}
+		res = append(res, provider.Stem{ID: id, Name: row[1]})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(res, func(a, b provider.Stem) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return res, nil
+}
+
+// readTSV reads a tab-separated file and calls fn for every row. The row
+// contains values of the given fields in the same order as the fields.
+func (d dumpio) readTSV(
+	file string,
+	fields []string,
+	fn func([]string) error,
+) error {
+	path := filepath.Join(d.dir, file)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	if !sc.Scan() {
+		if err = sc.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("file %s is empty", path)
+	}
+
+	header := strings.Split(sc.Text(), "\t")
+	idxs := make([]int, len(fields))
+	for i, field := range fields {
+		idx := slices.Index(header, field)
+		if idx == -1 {
+			return fmt.Errorf("file %s has no '%s' column", path, field)
+		}
+		idxs[i] = idx
+	}
+
+	row := make([]string, len(fields))
+	var line int
+	for sc.Scan() {
+		line++
+		txt := sc.Text()
+		if strings.TrimSpace(txt) == "" {
+			continue
+		}
+		vals := strings.Split(txt, "\t")
+		for i, idx := range idxs {
+			if idx >= len(vals) {
+				return fmt.Errorf("file %s, line %d: too few columns", path, line+1)
+			}
+			row[i] = strings.TrimSpace(vals[idx])
+		}
+		if err = fn(row); err != nil {
+			return fmt.Errorf("file %s, line %d: %w", path, line+1, err)
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,67 @@
+package dumpio_test
+
+import (
+	"testing"
+
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/io/dumpio"
+	"github.com/gnames/gnuuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStems(t *testing.T) {
+	assert := assert.New(t)
+	d := dumpio.New("testdata")
+	num, err := d.StemsNum()
+	assert.Nil(err)
+	assert.Equal(4, num)
+
+	var stems []provider.Stem
+	err = d.Stems(func(s provider.Stem) error {
+		stems = append(stems, s)
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal("Bubo bub", stems[0].Name)
+	assert.Equal(gnuuid.New("Bubo bub").String(), stems[0].ID)
+	assert.Equal("Pomatomus saltatrix", stems[3].Name)
+}
+
+func TestStemCanonicals(t *testing.T) {
+	assert := assert.New(t)
+	d := dumpio.New("testdata")
+	var res []provider.StemCanonical
+	err := d.StemCanonicals(func(sc provider.StemCanonical) error {
+		res = append(res, sc)
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(5, len(res))
+	assert.Equal("Bubo bub", res[0].Stem)
+	assert.Equal("Bubo bubo", res[0].Canonical)
+	last := res[len(res)-1]
+	assert.Equal("Pomatomus saltatrix", last.Canonical)
+	assert.Equal(gnuuid.New("Pomatomus saltatrix").String(), last.CanonicalID)
+	assert.Equal(11, last.DataSourceID)
+}
+
+func TestViruses(t *testing.T) {
+	assert := assert.New(t)
+	d := dumpio.New("testdata")
+	var res []provider.Virus
+	err := d.Viruses(func(v provider.Virus) error {
+		res = append(res, v)
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(3, len(res))
+	assert.Equal("Tobacco mosaic virus", res[0].Name)
+	assert.Equal(4, res[1].DataSourceID)
+	assert.Equal(gnuuid.New("Antarctic virus 1").String(), res[2].ID)
+}
+
+func TestMissingDump(t *testing.T) {
+	d := dumpio.New("no-such-dir")
+	_, err := d.StemsNum()
+	assert.NotNil(t, err)
+}
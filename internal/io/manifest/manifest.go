@@ -0,0 +1,249 @@
+// package manifest keeps metadata about the lookup cache: versions, the
+// source of the data, the number of records and checksums of files of
+// every cache component. The manifest is used to find out if the cache
+// is complete and up to date.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// FormatVersion is the version of the format of cached data. It has to be
+// increased every time the format of any cache component changes, so
+// caches created by older versions of gnmatcher are rebuilt.
+const FormatVersion = 1
+
+// File is the name of the manifest file in the cache directory.
+const File = "manifest.json"
+
+// Manifest describes the content of the cache directory.
+type Manifest struct {
+	// FormatVersion is the version of the format of the cached data.
+	FormatVersion int `json:"formatVersion"`
+
+	// Components contains metadata of every built cache component.
+	Components map[string]Component `json:"components"`
+}
+
+// Component describes one component of the cache, for example bloom
+// filters or stems key-value store.
+type Component struct {
+	// GnmatcherVersion is the version of gnmatcher that built the component.
+	GnmatcherVersion string `json:"gnmatcherVersion"`
+
+	// Source describes where the data came from.
+	Source Source `json:"source"`
+
+	// BuiltAt is the time when building of the component was finished.
+	BuiltAt time.Time `json:"builtAt"`
+
+	// UpdatedAt is the time of the last incremental update of the
+	// component, if there was any.
+	UpdatedAt time.Time `json:"updatedAt,omitzero"`
+
+	// RecordsNum is the number of records in the component.
+	RecordsNum int `json:"recordsNum"`
+
+	// Checksums contain SHA-256 sums of the component's files. The keys are
+	// paths relative to the component's directory. Components that are
+	// modified by reading (like key-value stores) do not have checksums.
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+// Source describes the origin of cached data.
+type Source struct {
+	// Name identifies the source, for example the name of the database,
+	// or the path to a dump.
+	Name string `json:"name"`
+
+	// UpdatedAt is the time of the last modification of the source, if
+	// it is known.
+	UpdatedAt time.Time `json:"updatedAt,omitzero"`
+}
+
+// Load reads the manifest from the cache directory. If there is no
+// manifest yet, it returns an empty one.
+func Load(cacheDir string) (Manifest, error) {
+	res := Manifest{
+		FormatVersion: FormatVersion,
+		Components:    make(map[string]Component),
+	}
+	bs, err := os.ReadFile(filepath.Join(cacheDir, File))
+	if errors.Is(err, fs.ErrNotExist) {
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	if err = json.Unmarshal(bs, &res); err != nil {
+		return res, fmt.Errorf("cannot decode manifest: %w", err)
+	}
+	if res.Components == nil {
+		res.Components = make(map[string]Component)
+	}
+	return res, nil
+}
+
+// Exists checks if the cache directory has a manifest file.
+func Exists(cacheDir string) bool {
+	_, err := os.Stat(filepath.Join(cacheDir, File))
+	return err == nil
+}
+
+// Save writes the manifest to the cache directory. The file is replaced
+// atomically, so a crash cannot leave a partially written manifest.
+func (m Manifest) Save(cacheDir string) error {
+	bs, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(cacheDir, File)
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, bs, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// NewComponent creates metadata for a component located at dir. If
+// withChecksums is true, checksums of all files of the component are
+// calculated.
+func NewComponent(
+	dir string,
+	version string,
+	src Source,
+	recordsNum int,
+	withChecksums bool,
+) (Component, error) {
+	res := Component{
+		GnmatcherVersion: version,
+		Source:           src,
+		BuiltAt:          time.Now().UTC(),
+		RecordsNum:       recordsNum,
+	}
+	if !withChecksums {
+		return res, nil
+	}
+	sums, err := checksums(dir)
+	if err != nil {
+		return res, err
+	}
+	res.Checksums = sums
+	return res, nil
+}
+
+// Update records changes of the component that were made after it was
+// built. It adds addedNum to the number of records and recalculates
+// checksums of the component's files.
+func (m Manifest) Update(cacheDir, name string, addedNum int) error {
+	c, ok := m.Components[name]
+	if !ok {
+		return fmt.Errorf("component '%s' is not in the manifest", name)
+	}
+	c.RecordsNum += addedNum
+	c.UpdatedAt = time.Now().UTC()
+	if len(c.Checksums) > 0 {
+		sums, err := checksums(filepath.Join(cacheDir, name))
+		if err != nil {
+			return err
+		}
+		c.Checksums = sums
+	}
+	m.Components[name] = c
+	return nil
+}
+
+// Check verifies that the component with the given name is complete, was
+// built from the given source with the current format, and that its files
+// were not changed since then. It returns nil if the component is valid,
+// otherwise it returns the reason why the component is not valid.
+func (m Manifest) Check(cacheDir, name string, src Source) error {
+	if m.FormatVersion != FormatVersion {
+		return fmt.Errorf(
+			"cache format version %d, expected %d",
+			m.FormatVersion, FormatVersion,
+		)
+	}
+	c, ok := m.Components[name]
+	if !ok {
+		return errors.New("component is missing or was not built completely")
+	}
+	if c.Source.Name != src.Name {
+		return fmt.Errorf(
+			"component was built from '%s', current source is '%s'",
+			c.Source.Name, src.Name,
+		)
+	}
+	if src.UpdatedAt.After(c.Source.UpdatedAt) {
+		return errors.New("source was updated after the component was built")
+	}
+
+	dir := filepath.Join(cacheDir, name)
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) == 0 {
+		return errors.New("component's directory is empty")
+	}
+	if len(c.Checksums) == 0 {
+		return nil
+	}
+
+	sums, err := checksums(dir)
+	if err != nil {
+		return err
+	}
+	for _, k := range slices.Sorted(maps.Keys(c.Checksums)) {
+		if sums[k] != c.Checksums[k] {
+			return fmt.Errorf("checksum of '%s' does not match", k)
+		}
+	}
+	return nil
+}
+
+// checksums calculates SHA-256 sums of all files in the directory.
+func checksums(dir string) (map[string]string, error) {
+	res := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSum(path)
+		if err != nil {
+			return err
+		}
+		res[rel] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func fileSum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
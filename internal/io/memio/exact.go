@@ -0,0 +1,53 @@
+package memio
+
+import (
+	"sync"
+
+	"github.com/gnames/gnmatcher/internal/ent/exact"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnuuid"
+)
+
+type exactMatcher struct {
+	data provider.DataProvider
+	ids  map[string]struct{}
+	mux  sync.RWMutex
+}
+
+// NewExactMatcher creates ExactMatcher that keeps IDs of stems from the
+// data provider in memory. Unlike bloom filters, it has no false
+// positives.
+func NewExactMatcher(data provider.DataProvider) exact.ExactMatcher {
+	return &exactMatcher{data: data}
+}
+
+func (em *exactMatcher) Init() error {
+	ids := make(map[string]struct{})
+	err := em.data.Stems(func(stem provider.Stem) error {
+		ids[stem.ID] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	em.mux.Lock()
+	em.ids = ids
+	em.mux.Unlock()
+	return nil
+}
+
+func (em *exactMatcher) MatchCanonicalID(uuid string) bool {
+	em.mux.RLock()
+	defer em.mux.RUnlock()
+	_, ok := em.ids[uuid]
+	return ok
+}
+
+func (em *exactMatcher) AddStems(data provider.DataProvider) error {
+	em.mux.Lock()
+	defer em.mux.Unlock()
+	return data.StemCanonicals(func(sc provider.StemCanonical) error {
+		em.ids[gnuuid.New(sc.Stem).String()] = struct{}{}
+		return nil
+	})
+}
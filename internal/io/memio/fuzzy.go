@@ -0,0 +1,149 @@
+package memio
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dvirsky/levenshtein"
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+)
+
+type fuzzyMatcher struct {
+	data  provider.DataProvider
+	trie  *levenshtein.MinTree
+	stems map[string][]mlib.MatchItem
+
+	// epithets contain stems by their specific epithets.
+	epithets map[string][]string
+	mux      sync.RWMutex
+}
+
+// NewFuzzyMatcher creates FuzzyMatcher that keeps a trie of stems and their
+// canonical forms from the data provider in memory.
+func NewFuzzyMatcher(data provider.DataProvider) fuzzy.FuzzyMatcher {
+	return &fuzzyMatcher{data: data}
+}
+
+func (fm *fuzzyMatcher) Init() error {
+	fm.mux.Lock()
+	defer fm.mux.Unlock()
+	fm.stems = make(map[string][]mlib.MatchItem)
+	fm.epithets = make(map[string][]string)
+	_, err := fm.addStems(fm.data)
+	return err
+}
+
+func (fm *fuzzyMatcher) MatchStem(stem string, opts fuzzy.Options) []string {
+	fm.mux.RLock()
+	defer fm.mux.RUnlock()
+	if fm.trie == nil {
+		return nil
+	}
+	return fm.trie.FuzzyMatches(stem, opts.MaxEditDist)
+}
+
+func (fm *fuzzyMatcher) MatchStemExact(stem string) bool {
+	fm.mux.RLock()
+	defer fm.mux.RUnlock()
+	_, ok := fm.stems[stem]
+	return ok
+}
+
+func (fm *fuzzyMatcher) StemToMatchItems(
+	stem string,
+) ([]mlib.MatchItem, error) {
+	fm.mux.RLock()
+	defer fm.mux.RUnlock()
+	return slices.Clone(fm.stems[stem]), nil
+}
+
+func (fm *fuzzyMatcher) EpithetToMatchItems(
+	epithet string,
+) ([]mlib.MatchItem, error) {
+	fm.mux.RLock()
+	defer fm.mux.RUnlock()
+	var res []mlib.MatchItem
+	for _, stem := range fm.epithets[epithet] {
+		res = append(res, fm.stems[stem]...)
+	}
+	return res, nil
+}
+
+func (fm *fuzzyMatcher) SpeciesToMatchItems(
+	species string,
+) ([]mlib.MatchItem, error) {
+	fm.mux.RLock()
+	defer fm.mux.RUnlock()
+	var res []mlib.MatchItem
+	for _, stem := range fm.epithets[fuzzy.SpecificEpithet(species)] {
+		if strings.HasPrefix(stem, species+" ") {
+			res = append(res, fm.stems[stem]...)
+		}
+	}
+	return res, nil
+}
+
+func (fm *fuzzyMatcher) AddStems(data provider.DataProvider) (int, error) {
+	fm.mux.Lock()
+	defer fm.mux.Unlock()
+	return fm.addStems(data)
+}
+
+// addStems merges stems and canonical forms from the data provider with
+// the known ones, and rebuilds the trie. Match items are replaced, not
+// modified, because they might be used by running requests.
+func (fm *fuzzyMatcher) addStems(data provider.DataProvider) (int, error) {
+	var num int
+	err := data.StemCanonicals(func(sc provider.StemCanonical) error {
+		items, ok := fm.stems[sc.Stem]
+		if !ok {
+			num++
+			if ep := fuzzy.SpecificEpithet(sc.Stem); ep != "" {
+				fm.epithets[ep] = append(
+					slices.Clone(fm.epithets[ep]), sc.Stem,
+				)
+			}
+		}
+		items = slices.Clone(items)
+		idx := slices.IndexFunc(items, func(mi mlib.MatchItem) bool {
+			return mi.ID == sc.CanonicalID
+		})
+		if idx == -1 {
+			items = append(items, mlib.MatchItem{
+				ID:       sc.CanonicalID,
+				MatchStr: sc.Canonical,
+			})
+			idx = len(items) - 1
+		}
+		dss := maps.Clone(items[idx].DataSourcesMap)
+		if dss == nil {
+			dss = make(map[int]struct{})
+		}
+		dss[sc.DataSourceID] = struct{}{}
+		items[idx].DataSourcesMap = dss
+		fm.stems[sc.Stem] = items
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(fm.stems) == 0 {
+		return 0, nil
+	}
+	stems := slices.Sorted(maps.Keys(fm.stems))
+	fm.trie, err = levenshtein.NewMinTree(stems)
+	if err != nil {
+		return 0, err
+	}
+	for _, v := range fm.stems {
+		slices.SortFunc(v, func(a, b mlib.MatchItem) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+	}
+	return num, nil
+}
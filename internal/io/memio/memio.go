@@ -0,0 +1,112 @@
+// package memio implements ExactMatcher, FuzzyMatcher and VirusMatcher
+// interfaces keeping all lookup data in memory. The matchers do not use
+// cache on disk and need neither gnames database nor a dump. They are
+// meant for small sets of names, for example in tests.
+package memio
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnparser"
+	"github.com/gnames/gnuuid"
+)
+
+// Name is a canonical form, or a name of a virus, with IDs of data-sources
+// that contain it.
+type Name struct {
+	Name        string
+	DataSources []int
+}
+
+type memio struct {
+	stems      []provider.Stem
+	canonicals []provider.StemCanonical
+	viruses    []provider.Virus
+}
+
+// NewProvider creates DataProvider from lists of canonical forms and
+// viruses. Stems of canonical forms are generated by gnparser. Viruses are
+// given a priority in the order they appear in the list.
+func NewProvider(canonicals, viruses []Name) provider.DataProvider {
+	var res memio
+	parser := gnparser.New(gnparser.NewConfig())
+	stems := make(map[string]struct{})
+	for _, v := range canonicals {
+		stem := v.Name
+		if prsd := parser.ParseName(v.Name); prsd.Parsed {
+			stem = prsd.Canonical.Stemmed
+		}
+		if _, ok := stems[stem]; !ok {
+			stems[stem] = struct{}{}
+			res.stems = append(res.stems, provider.Stem{
+				ID:   gnuuid.New(stem).String(),
+				Name: stem,
+			})
+		}
+
+		id := gnuuid.New(v.Name).String()
+		for _, ds := range v.DataSources {
+			res.canonicals = append(res.canonicals, provider.StemCanonical{
+				Stem:         stem,
+				CanonicalID:  id,
+				Canonical:    v.Name,
+				DataSourceID: ds,
+			})
+		}
+	}
+
+	for _, v := range viruses {
+		id := gnuuid.New(v.Name).String()
+		for _, ds := range v.DataSources {
+			res.viruses = append(res.viruses, provider.Virus{
+				ID:           id,
+				Name:         v.Name,
+				DataSourceID: ds,
+			})
+		}
+	}
+
+	slices.SortFunc(res.stems, func(a, b provider.Stem) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	slices.SortFunc(res.canonicals, func(a, b provider.StemCanonical) int {
+		if res := strings.Compare(a.Stem, b.Stem); res != 0 {
+			return res
+		}
+		return strings.Compare(a.CanonicalID, b.CanonicalID)
+	})
+	return res
+}
+
+func (m memio) StemsNum() (int, error) {
+	return len(m.stems), nil
+}
+
+func (m memio) Stems(fn func(provider.Stem) error) error {
+	for _, v := range m.stems {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m memio) StemCanonicals(fn func(provider.StemCanonical) error) error {
+	for _, v := range m.canonicals {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m memio) Viruses(fn func(provider.Virus) error) error {
+	for _, v := range m.viruses {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package memio
+
+import (
+	"strings"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/ent/virus"
+)
+
+type virusMatcher struct {
+	data  provider.DataProvider
+	names []string
+	items []mlib.MatchItem
+}
+
+// NewVirusMatcher creates VirusMatcher that keeps names of viruses from the
+// data provider in memory.
+func NewVirusMatcher(data provider.DataProvider) virus.VirusMatcher {
+	return &virusMatcher{data: data}
+}
+
+func (vm *virusMatcher) Init() error {
+	idx := make(map[string]int)
+	var names []string
+	var items []mlib.MatchItem
+	err := vm.data.Viruses(func(vr provider.Virus) error {
+		i, ok := idx[vr.ID]
+		if !ok {
+			i = len(items)
+			idx[vr.ID] = i
+			names = append(names, string(vm.NameToBytes(vr.Name)))
+			items = append(items, mlib.MatchItem{
+				ID:             vr.ID,
+				MatchStr:       vr.Name,
+				MatchType:      vlib.Virus,
+				DataSourcesMap: make(map[int]struct{}),
+			})
+		}
+		items[i].DataSourcesMap[vr.DataSourceID] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	vm.names = names
+	vm.items = items
+	return nil
+}
+
+func (vm *virusMatcher) MatchVirus(s string) ([]mlib.MatchItem, error) {
+	prefix := string(vm.NameToBytes(s))
+	var res []mlib.MatchItem
+	for i := range vm.names {
+		if !strings.HasPrefix(vm.names[i], prefix) {
+			continue
+		}
+		res = append(res, vm.items[i])
+		if len(res) == virus.Limit {
+			break
+		}
+	}
+	return res, nil
+}
+
+func (vm *virusMatcher) MatchVirusFuzzy(s string) ([]mlib.MatchItem, error) {
+	var cands []virus.Candidate
+	for i := range vm.items {
+		approx, ok := virus.Approximate(s, vm.items[i].MatchStr)
+		if !ok {
+			continue
+		}
+		cands = append(cands, virus.Candidate{Item: vm.items[i], Approx: approx})
+	}
+	return virus.Select(cands), nil
+}
+
+func (vm *virusMatcher) NameToBytes(name string) []byte {
+	name = strings.ToLower(name)
+	words := strings.Fields(name)
+	return []byte("\x00" + strings.Join(words, " "))
+}
@@ -0,0 +1,105 @@
+package namesio_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/io/namesio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		msg, input string
+		format     namesio.InputFormat
+		column     string
+		res        []string
+	}{
+		{"lines", "Bubo bubo\n\nPardosa moesta \n", namesio.Lines, "",
+			[]string{"Bubo bubo", "", "Pardosa moesta"}},
+		{"csv first", "name,id\nBubo bubo,1\n\"Pardosa, moesta\",2\n",
+			namesio.CSVInput, "", []string{"Bubo bubo", "Pardosa, moesta"}},
+		{"csv header", "id,Name\n1,Bubo bubo\n2\n", namesio.CSVInput, "name",
+			[]string{"Bubo bubo", ""}},
+		{"tsv number", "id\tname\n1\tBubo bubo\n", namesio.TSVInput, "2",
+			[]string{"Bubo bubo"}},
+	}
+
+	for _, v := range tests {
+		r, err := namesio.NewReader(strings.NewReader(v.input), v.format, v.column)
+		assert.Nil(err, v.msg)
+		var res []string
+		for {
+			names, err := r.Batch(2)
+			res = append(res, names...)
+			if err == io.EOF {
+				break
+			}
+			assert.Nil(err, v.msg)
+		}
+		assert.Equal(v.res, res, v.msg)
+	}
+
+	_, err := namesio.NewReader(strings.NewReader("id,name\n"),
+		namesio.CSVInput, "3")
+	assert.NotNil(err)
+	_, err = namesio.NewReader(strings.NewReader("id,name\n"),
+		namesio.CSVInput, "scientificName")
+	assert.NotNil(err)
+}
+
+func TestDetectInputFormat(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(namesio.CSVInput, namesio.DetectInputFormat("names.CSV"))
+	assert.Equal(namesio.TSVInput, namesio.DetectInputFormat("names.tsv"))
+	assert.Equal(namesio.Lines, namesio.DetectInputFormat("names.txt"))
+	assert.Equal(namesio.Lines, namesio.DetectInputFormat("-"))
+}
+
+func TestWriter(t *testing.T) {
+	assert := assert.New(t)
+	ms := []mlib.Match{
+		{
+			ID:        "1",
+			Name:      "Bubo bubo",
+			MatchType: vlib.Exact,
+			MatchItems: []mlib.MatchItem{
+				{ID: "a", MatchStr: "Bubo bubo", MatchType: vlib.Exact,
+					DataSources: []int{1, 3}},
+				{ID: "b", MatchStr: "Bubo bubo", MatchType: vlib.Exact},
+			},
+		},
+		{ID: "2", Name: "Xyz", MatchType: vlib.NoMatch},
+	}
+
+	var b strings.Builder
+	w := namesio.NewWriter(&b, namesio.CSV)
+	assert.Nil(w.Write(ms[:1]))
+	assert.Nil(w.Write(ms[1:]))
+	assert.Nil(w.Close())
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(4, len(lines))
+	assert.True(strings.HasPrefix(lines[0], "Index,Id,Name,MatchType"))
+	assert.Equal("0,1,Bubo bubo,Exact,a,Bubo bubo,Exact,0,0,1|3", lines[1])
+	assert.Equal("1,2,Xyz,NoMatch,,,,,,", lines[3])
+
+	b.Reset()
+	w = namesio.NewWriter(&b, namesio.JSONL)
+	assert.Nil(w.Write(ms))
+	assert.Nil(w.Close())
+	lines = strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(2, len(lines))
+	assert.Contains(lines[1], `"input":"Xyz"`)
+
+	b.Reset()
+	w = namesio.NewWriter(&b, namesio.JSON)
+	assert.Nil(w.Close())
+	assert.Equal("[]\n", b.String())
+
+	_, err := namesio.NewFormat("xml")
+	assert.NotNil(err)
+}
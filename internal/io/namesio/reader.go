@@ -0,0 +1,149 @@
+// package namesio reads name-strings from files or STDIN, and writes
+// results of their matching in CSV, TSV, JSON or JSON lines formats.
+package namesio
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// InputFormat describes how name-strings are stored in the input.
+type InputFormat string
+
+const (
+	// Lines input contains one name-string per line.
+	Lines InputFormat = "lines"
+
+	// CSVInput is a comma-separated file with a header. Name-strings are
+	// taken from one of its columns.
+	CSVInput InputFormat = "csv"
+
+	// TSVInput is a tab-separated file with a header. Name-strings are
+	// taken from one of its columns.
+	TSVInput InputFormat = "tsv"
+)
+
+// NewInputFormat converts a string to InputFormat. An empty string
+// gives an empty format, that means the format is detected from the
+// extension of the file.
+func NewInputFormat(s string) (InputFormat, error) {
+	f := InputFormat(strings.ToLower(s))
+	if f == "" || slices.Contains([]InputFormat{Lines, CSVInput, TSVInput}, f) {
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown input format '%s'", s)
+}
+
+// DetectInputFormat finds out the input format from the extension of the
+// file. Files with unknown extensions, and STDIN, are read as lines.
+func DetectInputFormat(path string) InputFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return CSVInput
+	case ".tsv":
+		return TSVInput
+	default:
+		return Lines
+	}
+}
+
+// Reader reads name-strings in batches.
+type Reader struct {
+	lines *bufio.Scanner
+	rows  *csv.Reader
+	col   int
+}
+
+// NewReader creates a Reader of name-strings. For CSV and TSV inputs the
+// column is either the name of a column in the header, or its number,
+// starting from 1. If the column is empty, the first column is used.
+func NewReader(r io.Reader, f InputFormat, column string) (*Reader, error) {
+	if f == Lines {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		return &Reader{lines: sc}, nil
+	}
+
+	rows := csv.NewReader(r)
+	rows.FieldsPerRecord = -1
+	rows.LazyQuotes = true
+	if f == TSVInput {
+		rows.Comma = '\t'
+	}
+	header, err := rows.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read header: %w", err)
+	}
+	col, err := columnIndex(header, column)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{rows: rows, col: col}, nil
+}
+
+// columnIndex finds the index of the column by its name or number.
+func columnIndex(header []string, column string) (int, error) {
+	if column == "" {
+		return 0, nil
+	}
+	for i, v := range header {
+		if strings.EqualFold(strings.TrimSpace(v), column) {
+			return i, nil
+		}
+	}
+	num, err := strconv.Atoi(column)
+	if err != nil {
+		return 0, fmt.Errorf("cannot find column '%s' in the header", column)
+	}
+	if num < 1 || num > len(header) {
+		return 0, fmt.Errorf(
+			"column number %d is out of range 1-%d", num, len(header),
+		)
+	}
+	return num - 1, nil
+}
+
+// Batch returns up to size next name-strings. When the input is
+// exhausted, it returns io.EOF together with the last names.
+func (r *Reader) Batch(size int) ([]string, error) {
+	res := make([]string, 0, size)
+	for len(res) < size {
+		name, err := r.next()
+		if errors.Is(err, io.EOF) {
+			return res, io.EOF
+		}
+		if err != nil {
+			return res, err
+		}
+		res = append(res, name)
+	}
+	return res, nil
+}
+
+func (r *Reader) next() (string, error) {
+	if r.lines != nil {
+		if r.lines.Scan() {
+			return strings.TrimSpace(r.lines.Text()), nil
+		}
+		if err := r.lines.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+
+	row, err := r.rows.Read()
+	if err != nil {
+		return "", err
+	}
+	if r.col >= len(row) {
+		return "", nil
+	}
+	return strings.TrimSpace(row[r.col]), nil
+}
@@ -0,0 +1,151 @@
+package namesio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gnames/gnfmt"
+	mlib "github.com/gnames/gnlib/ent/matcher"
+)
+
+// Format is the format of the output.
+type Format string
+
+const (
+	// CSV output has a row for every match item of a name-string.
+	CSV Format = "csv"
+
+	// TSV output is the same as CSV, but values are separated by tabs.
+	TSV Format = "tsv"
+
+	// JSON output is an array of matches.
+	JSON Format = "json"
+
+	// JSONL output has one match per line.
+	JSONL Format = "jsonl"
+)
+
+// NewFormat converts a string to Format.
+func NewFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(s))
+	if slices.Contains([]Format{CSV, TSV, JSON, JSONL}, f) {
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format '%s'", s)
+}
+
+var header = []string{
+	"Index", "Id", "Name", "MatchType", "MatchId", "MatchStr",
+	"MatchItemType", "EditDistance", "EditDistanceStem", "DataSources",
+}
+
+// Writer writes matches of name-strings in batches.
+type Writer struct {
+	w      io.Writer
+	f      Format
+	index  int
+	closed bool
+}
+
+// NewWriter creates Writer of matches in the given format.
+func NewWriter(w io.Writer, f Format) *Writer {
+	return &Writer{w: w, f: f}
+}
+
+// Write writes a batch of matches. Matches keep the order of the input,
+// their index continues from the previous batch.
+func (w *Writer) Write(ms []mlib.Match) error {
+	var b strings.Builder
+	for _, v := range ms {
+		switch w.f {
+		case CSV, TSV:
+			if w.index == 0 {
+				w.writeRow(&b, header)
+			}
+			w.writeRows(&b, v)
+		case JSON, JSONL:
+			bs, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if w.f == JSON {
+				if w.index == 0 {
+					b.WriteString("[\n")
+				} else {
+					b.WriteString(",\n")
+				}
+			}
+			b.Write(bs)
+			if w.f == JSONL {
+				b.WriteString("\n")
+			}
+		}
+		w.index++
+	}
+	_, err := io.WriteString(w.w, b.String())
+	return err
+}
+
+// Close finishes the output. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	var s string
+	switch {
+	case w.f == JSON && w.index == 0:
+		s = "[]\n"
+	case w.f == JSON:
+		s = "\n]\n"
+	case (w.f == CSV || w.f == TSV) && w.index == 0:
+		s = gnfmt.ToCSV(header, w.sep()) + "\n"
+	}
+	_, err := io.WriteString(w.w, s)
+	return err
+}
+
+func (w *Writer) writeRow(b *strings.Builder, row []string) {
+	b.WriteString(gnfmt.ToCSV(row, w.sep()))
+	b.WriteString("\n")
+}
+
+func (w *Writer) sep() rune {
+	if w.f == TSV {
+		return '\t'
+	}
+	return ','
+}
+
+// writeRows writes a row for every match item. If there are no match
+// items, the row contains only data of the name-string.
+func (w *Writer) writeRows(b *strings.Builder, m mlib.Match) {
+	name := []string{
+		strconv.Itoa(w.index), m.ID, m.Name, m.MatchType.String(),
+	}
+	if len(m.MatchItems) == 0 {
+		row := append(name, "", "", "", "", "", "")
+		w.writeRow(b, row)
+		return
+	}
+	for _, v := range m.MatchItems {
+		dss := make([]string, len(v.DataSources))
+		for i := range v.DataSources {
+			dss[i] = strconv.Itoa(v.DataSources[i])
+		}
+		row := append(slices.Clone(name),
+			v.ID,
+			v.MatchStr,
+			v.MatchType.String(),
+			strconv.Itoa(v.EditDistance),
+			strconv.Itoa(v.EditDistanceStem),
+			strings.Join(dss, "|"),
+		)
+		w.writeRow(b, row)
+	}
+}
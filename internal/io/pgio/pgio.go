@@ -0,0 +1,110 @@
+// package pgio implements DataProvider interface using gnames PostgreSQL
+// database.
+package pgio
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/io/dbase"
+	"github.com/gnames/gnmatcher/pkg/config"
+)
+
+type pgio struct {
+	cfg config.Config
+}
+
+// New creates DataProvider that takes data from gnames database. Every
+// method opens its own connection to the database and closes it when done.
+func New(cfg config.Config) provider.DataProvider {
+	return pgio{cfg: cfg}
+}
+
+func (p pgio) StemsNum() (int, error) {
+	db, err := dbase.NewDB(p.cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	q := "SELECT count(*) from canonical_stems"
+	var num int
+	row := db.QueryRow(q)
+	if err := row.Scan(&num); err != nil {
+		return 0, err
+	}
+	return num, nil
+}
+
+func (p pgio) Stems(fn func(provider.Stem) error) error {
+	q := "SELECT id, name FROM canonical_stems order by name"
+	return p.query(q, func(rows *sql.Rows) error {
+		var stem provider.Stem
+		if err := rows.Scan(&stem.ID, &stem.Name); err != nil {
+			return err
+		}
+		return fn(stem)
+	})
+}
+
+func (p pgio) StemCanonicals(fn func(provider.StemCanonical) error) error {
+	q := `SELECT s.name as name_stem, c.name, c.id, nsi.data_source_id
+          FROM canonical_stems s
+            JOIN name_strings ns
+              ON ns.canonical_stem_id = s.id
+            JOIN canonicals c
+              ON ns.canonical_id = c.id
+            JOIN name_string_indices nsi
+              ON ns.id = nsi.name_string_id
+        GROUP BY c.name, c.id, s.name, nsi.data_source_id
+          ORDER BY name_stem, c.id`
+
+	return p.query(q, func(rows *sql.Rows) error {
+		var sc provider.StemCanonical
+		err := rows.Scan(&sc.Stem, &sc.Canonical, &sc.CanonicalID, &sc.DataSourceID)
+		if err != nil {
+			slog.Error("Cannot read stem data from query", "error", err)
+			return err
+		}
+		return fn(sc)
+	})
+}
+
+func (p pgio) Viruses(fn func(provider.Virus) error) error {
+	q := `SELECT name_string_id, name, ds.id
+  FROM verification v
+    JOIN data_sources ds ON ds.id = v.data_source_id
+  WHERE virus='true'
+  ORDER by ds.is_curated desc, name_string_id`
+
+	return p.query(q, func(rows *sql.Rows) error {
+		var v provider.Virus
+		if err := rows.Scan(&v.ID, &v.Name, &v.DataSourceID); err != nil {
+			return err
+		}
+		return fn(v)
+	})
+}
+
+// query runs a query and calls fn for every row of the result.
+func (p pgio) query(q string, fn func(*sql.Rows) error) error {
+	db, err := dbase.NewDB(p.cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
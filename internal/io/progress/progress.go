@@ -0,0 +1,80 @@
+// package progress reports advancement of long-running stages, such as
+// building of lookup caches.
+package progress
+
+import (
+	"log/slog"
+	"time"
+)
+
+// interval is the minimal time between two progress reports.
+const interval = 10 * time.Second
+
+// checkEvery sets how many records are processed between checks of time.
+const checkEvery = 1_000
+
+// Progress keeps track of processed records of a stage and periodically
+// logs the number of records, elapsed time and, if the total number of
+// records is known, estimated time left.
+type Progress struct {
+	stage    string
+	total    int
+	count    int
+	start    time.Time
+	reported time.Time
+}
+
+// New creates Progress for a stage. If total is not known, it should be 0.
+func New(stage string, total int) *Progress {
+	now := time.Now()
+	slog.Info("Stage started", "stage", stage, "total", total)
+	return &Progress{
+		stage:    stage,
+		total:    total,
+		start:    now,
+		reported: now,
+	}
+}
+
+// Inc adds one processed record.
+func (p *Progress) Inc() {
+	p.count++
+	if p.count%checkEvery != 0 || time.Since(p.reported) < interval {
+		return
+	}
+	p.reported = time.Now()
+	p.report()
+}
+
+// Count returns the number of processed records.
+func (p *Progress) Count() int {
+	return p.count
+}
+
+// Finish reports the end of the stage.
+func (p *Progress) Finish() {
+	slog.Info("Stage finished",
+		"stage", p.stage,
+		"records", p.count,
+		"elapsed", time.Since(p.start).Round(time.Millisecond).String(),
+	)
+}
+
+func (p *Progress) report() {
+	elapsed := time.Since(p.start)
+	attrs := []any{
+		"stage", p.stage,
+		"records", p.count,
+		"elapsed", elapsed.Round(time.Second).String(),
+	}
+	if p.total > 0 && p.count > 0 && p.count <= p.total {
+		percent := 100 * float64(p.count) / float64(p.total)
+		left := float64(elapsed) * float64(p.total-p.count) / float64(p.count)
+		attrs = append(attrs,
+			"total", p.total,
+			"percent", int(percent),
+			"eta", time.Duration(left).Round(time.Second).String(),
+		)
+	}
+	slog.Info("Stage progress", attrs...)
+}
@@ -2,17 +2,65 @@ package rest_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gnames/gnfmt"
 	mlib "github.com/gnames/gnlib/ent/matcher"
 	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/io/rest"
+	gnmatcher "github.com/gnames/gnmatcher/pkg"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
 	"github.com/stretchr/testify/assert"
 )
 
+// bugsFixture contains lookup data that are needed for regression tests,
+// so the tests do not depend on gnames database.
+var bugsFixture = gnmatcher.Fixture{
+	Canonicals: []gnmatcher.FixtureName{
+		{Name: "Tillandsia utriculata", DataSources: []int{1}},
+		{Name: "Phegoptera", DataSources: []int{1}},
+		{Name: "Drosophila melanogaster", DataSources: []int{1, 3}},
+		{Name: "Acacia", DataSources: []int{1}},
+		{Name: "Acacia horrida", DataSources: []int{1}},
+		{Name: "Bubo", DataSources: []int{1}},
+		{Name: "Bubo bubo", DataSources: []int{1, 3}},
+		{Name: "Isoetes longissima", DataSources: []int{1}},
+		{Name: "Vesicaria", DataSources: []int{1}},
+		{Name: "Vesicaria cretica", DataSources: []int{1}},
+		{Name: "Pleurotoma", DataSources: []int{1}},
+		{Name: "Pleurotoma anita", DataSources: []int{1}},
+		{Name: "Teucrium pyrenaicum", DataSources: []int{1, 196}},
+		{Name: "Teucrium pyrenaicum guarensis", DataSources: []int{196}},
+	},
+	Viruses: []gnmatcher.FixtureName{
+		{Name: "Tobacco mosaic virus", DataSources: []int{1}},
+	},
+}
+
+// bugsURL starts the service with lookup data from bugsFixture and returns
+// the URL of its API.
+func bugsURL(t *testing.T) string {
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	t.Cleanup(func() { slog.SetLogLoggerLevel(oldLevel) })
+
+	gnm := gnmatcher.NewWithFixture(config.New(), bugsFixture)
+	err := gnm.Init()
+	assert.Nil(t, err)
+
+	service := rest.NewMatcherService(gnm, 0, gnfmt.GNjson{})
+	srv := httptest.NewServer(rest.Handler(service))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/api/v1/"
+}
+
 var bugs = []struct {
 	msg, name, matchCanonical string
 	matchType                 vlib.MatchTypeValue
@@ -105,6 +153,7 @@ var bugs = []struct {
 }
 
 func TestBugs(t *testing.T) {
+	url := bugsURL(t)
 	enc := gnfmt.GNjson{}
 	req, err := enc.Encode(params())
 	assert.Nil(t, err)
@@ -152,6 +201,7 @@ func TestBugs(t *testing.T) {
 // Test #47: Make sure that infraspecies do match as fuzzy even if their
 // stems are the same as matched name.
 func TestFuzzyInfrasp(t *testing.T) {
+	url := bugsURL(t)
 	assert := assert.New(t)
 	params := mlib.Input{
 		Names:       []string{"Teucrium pyrenaicum subsp. guarense"},
@@ -185,3 +235,55 @@ func params() mlib.Input {
 	}
 	return mlib.Input{Names: ns}
 }
+
+// TestRunShutdown checks that Run stops the server and returns when its
+// context is canceled.
+func TestRunShutdown(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	gnm := gnmatcher.NewWithFixture(config.New(), bugsFixture)
+	assert.Nil(gnm.Init())
+	defer gnm.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	service := rest.NewMatcherService(gnm, 0, gnfmt.GNjson{})
+	assert.Nil(rest.Run(ctx, service))
+}
+
+// TestMatchCanceled checks that an interrupted request returns matches with
+// errors and 503 status.
+func TestMatchCanceled(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	gnm := gnmatcher.NewWithFixture(config.New(), bugsFixture)
+	assert.Nil(gnm.Init())
+	defer gnm.Close()
+	service := rest.NewMatcherService(gnm, 0, gnfmt.GNjson{})
+
+	enc := gnfmt.GNjson{}
+	inp := params()
+	req, err := enc.Encode(inp)
+	assert.Nil(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequestWithContext(
+		ctx, http.MethodPost, "/api/v1/matches", bytes.NewReader(req),
+	)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	rest.Handler(service).ServeHTTP(w, r)
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+
+	var res output.Output
+	assert.Nil(enc.Decode(w.Body.Bytes(), &res))
+	assert.Equal(len(inp.Names), len(res.Matches))
+	assert.Equal(len(inp.Names), res.ErrorsNum)
+	for _, v := range res.Matches {
+		assert.NotEmpty(v.Error)
+	}
+}
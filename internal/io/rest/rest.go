@@ -1,6 +1,9 @@
 package rest
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -10,6 +13,8 @@ import (
 	"time"
 
 	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnlib/ent/nomcode"
+	gnmatcher "github.com/gnames/gnmatcher/pkg"
 	"github.com/gnames/gnmatcher/pkg/config"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -17,11 +22,59 @@ import (
 
 var apiPath = "/api/v1/"
 
+// shutdownTimeout is the time given to running requests to finish after
+// the server is asked to stop.
+const shutdownTimeout = 5 * time.Minute
+
 // Run creates and runs a RESTful API service of gnmatcher.
 // this API is described by OpenAPI schema at
 // https://apidoc.gnames.org/gnmatcher
-func Run(m MatcherService) {
+//
+// The server runs until the context is canceled. Then it stops accepting
+// new requests and waits for running ones to finish. Run returns an error
+// if the server could not start, or if it did not stop gracefully.
+func Run(ctx context.Context, m MatcherService) error {
 	slog.Info("Starting HTTP API server", "port", m.Port())
+	e := newEcho(m)
+
+	addr := fmt.Sprintf(":%d", m.Port())
+	s := &http.Server{
+		Addr:         addr,
+		ReadTimeout:  5 * time.Minute,
+		WriteTimeout: 5 * time.Minute,
+	}
+
+	chErr := make(chan error, 1)
+	go func() {
+		chErr <- e.StartServer(s)
+	}()
+
+	select {
+	case err := <-chErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Stopping HTTP API server")
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.Shutdown(sctx); err != nil {
+		return err
+	}
+	if err := <-chErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Handler returns http.Handler with all endpoints of the service. It
+// allows to serve the API without Run, for example by httptest.Server.
+func Handler(m MatcherService) http.Handler {
+	return newEcho(m)
+}
+
+// newEcho creates echo instance with middleware and routes of the API.
+func newEcho(m MatcherService) *echo.Echo {
 	e := echo.New()
 	e.Use(middleware.Gzip())
 	e.Use(middleware.CORS())
@@ -33,14 +86,9 @@ func Run(m MatcherService) {
 	e.GET(apiPath+"version", ver(m))
 	e.POST(apiPath+"matches", matchPOST(m))
 	e.GET(apiPath+"matches/:names", matchGET(m))
-
-	addr := fmt.Sprintf(":%d", m.Port())
-	s := &http.Server{
-		Addr:         addr,
-		ReadTimeout:  5 * time.Minute,
-		WriteTimeout: 5 * time.Minute,
-	}
-	e.Logger.Fatal(e.StartServer(s))
+	e.POST(apiPath+"admin/reload", reload(m))
+	e.POST(apiPath+"admin/update", update(m))
+	return e
 }
 
 func root(c echo.Context) error {
@@ -71,37 +119,153 @@ func ver(m MatcherService) func(echo.Context) error {
 	}
 }
 
-func matchGET(m MatcherService) func(echo.Context) error {
-	return func(c echo.Context) error {
-		nameStr, _ := url.QueryUnescape(c.Param("names"))
-		names := strings.Split(nameStr, "|")
-		dsStr, _ := url.QueryUnescape(c.QueryParam("data_sources"))
-		var ds []int
-		for _, v := range strings.Split(dsStr, "|") {
-			if id, err := strconv.Atoi(v); err == nil {
-				ds = append(ds, id)
-			}
-		}
-		var opts []config.Option
-		spGrp := c.QueryParam("species_group") == "true"
-		if spGrp {
-			opts = append(opts, config.OptWithSpeciesGroup(true))
-		}
+// matchInput extends the input of gnlib with options that are specific
+// to gnmatcher.
+type matchInput struct {
+	mlib.Input
+
+	// BestItemsNum limits match items of a name to the given number of
+	// items with the highest scores.
+	BestItemsNum int `json:"bestItemsNum,omitempty"`
+
+	// MinScore removes match items with lower scores.
+	MinScore float64 `json:"minScore,omitempty"`
+
+	// WithExplain adds explanations of matching to the results.
+	WithExplain bool `json:"withExplain,omitempty"`
+
+	// WithBatchContext boosts fuzzy and partial match items with genera
+	// from exact matches of other names of the request.
+	WithBatchContext bool `json:"withBatchContext,omitempty"`
+
+	// NomCode is the nomenclatural code of the names, for example
+	// 'botanical', 'zoological', 'bacterial' or 'cultivars'.
+	NomCode string `json:"nomCode,omitempty"`
+
+	// WithAllPartials makes partial matching to return matches of all
+	// truncated versions of a name.
+	WithAllPartials bool `json:"withAllPartials,omitempty"`
+
+	// WithAdaptiveEditDist makes the maximal edit distance of fuzzy
+	// matching to depend on the length of a name.
+	WithAdaptiveEditDist bool `json:"withAdaptiveEditDist,omitempty"`
+
+	// WithOCRDistance makes fuzzy matching to use weighted edit distance
+	// for typical OCR errors.
+	WithOCRDistance bool `json:"withOCRDistance,omitempty"`
+
+	// WithWordsChange allows matching of names with merged or split words.
+	WithWordsChange bool `json:"withWordsChange,omitempty"`
+
+	// WithRecombinations allows epithet matching to return names with a
+	// genus that differs from the genus of the input.
+	WithRecombinations bool `json:"withRecombinations,omitempty"`
+}
+
+// opts converts the input to matching options.
+func (inp matchInput) opts() []config.Option {
+	var res []config.Option
+	if inp.WithSpeciesGroup {
+		res = append(res, config.OptWithSpeciesGroup(true))
+	}
+	if inp.WithRelaxedFuzzyMatch {
+		res = append(res, config.OptWithRelaxedFuzzyMatch(true))
+	}
+	if inp.WithUninomialFuzzyMatch {
+		res = append(res, config.OptWithUninomialFuzzyMatch(true))
+	}
+	if len(inp.DataSources) > 0 {
+		res = append(res, config.OptDataSources(inp.DataSources))
+	}
+	if inp.BestItemsNum > 0 {
+		res = append(res, config.OptBestItemsNum(inp.BestItemsNum))
+	}
+	if inp.MinScore > 0 {
+		res = append(res, config.OptMinScore(inp.MinScore))
+	}
+	if inp.WithExplain {
+		res = append(res, config.OptWithExplain(true))
+	}
+	if inp.WithBatchContext {
+		res = append(res, config.OptWithBatchContext(true))
+	}
+	if inp.WithOCRDistance {
+		res = append(res, config.OptWithOCRDistance(true))
+	}
+	if inp.WithAdaptiveEditDist {
+		res = append(res, config.OptWithAdaptiveEditDist(true))
+	}
+	if inp.WithAllPartials {
+		res = append(res, config.OptWithAllPartials(true))
+	}
+	if inp.WithWordsChange {
+		res = append(res, config.OptWithWordsChange(true))
+	}
+	if inp.WithRecombinations {
+		res = append(res, config.OptWithRecombinations(true))
+	}
+	if inp.NomCode != "" {
+		res = append(res, config.OptNomCode(nomcode.New(inp.NomCode)))
+	}
+	return res
+}
 
-		fuzzyRelaxed := c.QueryParam("fuzzy_relaxed") == "true"
-		if fuzzyRelaxed {
-			opts = append(opts, config.OptWithRelaxedFuzzyMatch(true))
+// queryInput creates matchInput from names and query parameters of a GET
+// request.
+func queryInput(c echo.Context) matchInput {
+	nameStr, _ := url.QueryUnescape(c.Param("names"))
+	dsStr, _ := url.QueryUnescape(c.QueryParam("data_sources"))
+	var ds []int
+	for _, v := range strings.Split(dsStr, "|") {
+		if id, err := strconv.Atoi(v); err == nil {
+			ds = append(ds, id)
 		}
+	}
+
+	inp := matchInput{
+		Input: mlib.Input{
+			Names:                   strings.Split(nameStr, "|"),
+			WithSpeciesGroup:        c.QueryParam("species_group") == "true",
+			WithRelaxedFuzzyMatch:   c.QueryParam("fuzzy_relaxed") == "true",
+			WithUninomialFuzzyMatch: c.QueryParam("fuzzy_uninomial") == "true",
+			DataSources:             ds,
+		},
+	}
+	inp.BestItemsNum, _ = strconv.Atoi(c.QueryParam("best_items"))
+	inp.MinScore, _ = strconv.ParseFloat(c.QueryParam("min_score"), 64)
+	inp.WithExplain = c.QueryParam("explain") == "true"
+	inp.WithOCRDistance = c.QueryParam("ocr") == "true"
+	inp.WithBatchContext = c.QueryParam("batch_context") == "true"
+	inp.WithAdaptiveEditDist = c.QueryParam("adaptive_edit_dist") == "true"
+	inp.NomCode = c.QueryParam("code")
+	inp.WithAllPartials = c.QueryParam("all_partials") == "true"
+	inp.WithRecombinations = c.QueryParam("recombinations") == "true"
+	inp.WithWordsChange = c.QueryParam("words_change") == "true"
+	return inp
+}
 
-		fuzzyUni := c.QueryParam("fuzzy_uninomial") == "true"
-		if fuzzyUni {
-			opts = append(opts, config.OptWithUninomialFuzzyMatch(true))
+func matchGET(m MatcherService) func(echo.Context) error {
+	return func(c echo.Context) error {
+		inp := queryInput(c)
+		names := inp.Names
+		opts := inp.opts()
+
+		ctx := c.Request().Context()
+		result, err := m.MatchNamesDetailed(ctx, names, opts...)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			slog.Warn("Names match was interrupted",
+				"namesNum", len(names),
+				"method", "GET",
+				"error", ctxErr)
+			return c.JSON(http.StatusServiceUnavailable, result)
 		}
-		if len(ds) > 0 {
-			opts = append(opts, config.OptDataSources(ds))
+		if err != nil {
+			slog.Error("Names match had errors",
+				"namesNum", len(names),
+				"errorsNum", result.ErrorsNum,
+				"method", "GET",
+				"error", err)
 		}
-
-		result := m.MatchNames(names, opts...)
 		if l := len(names); l > 0 {
 			slog.Info("Names match",
 				"namesNum", l,
@@ -114,26 +278,28 @@ func matchGET(m MatcherService) func(echo.Context) error {
 
 func matchPOST(m MatcherService) func(echo.Context) error {
 	return func(c echo.Context) error {
-		var inp mlib.Input
-		var opts []config.Option
-
+		var inp matchInput
 		if err := c.Bind(&inp); err != nil {
 			return err
 		}
-		if inp.WithSpeciesGroup {
-			opts = append(opts, config.OptWithSpeciesGroup(true))
-		}
-		if inp.WithRelaxedFuzzyMatch {
-			opts = append(opts, config.OptWithRelaxedFuzzyMatch(true))
-		}
-		if inp.WithUninomialFuzzyMatch {
-			opts = append(opts, config.OptWithUninomialFuzzyMatch(true))
+		opts := inp.opts()
+
+		ctx := c.Request().Context()
+		result, err := m.MatchNamesDetailed(ctx, inp.Names, opts...)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			slog.Warn("Names match was interrupted",
+				"namesNum", len(inp.Names),
+				"method", "POST",
+				"error", ctxErr)
+			return c.JSON(http.StatusServiceUnavailable, result)
 		}
-		if len(inp.DataSources) > 0 {
-			opts = append(opts, config.OptDataSources(inp.DataSources))
+		if err != nil {
+			slog.Error("Names match had errors",
+				"namesNum", len(inp.Names),
+				"errorsNum", result.ErrorsNum,
+				"method", "POST",
+				"error", err)
 		}
-
-		result := m.MatchNames(inp.Names, opts...)
 		if l := len(inp.Names); l > 0 {
 			slog.Info("Names match",
 				"namesNum", l,
@@ -143,3 +309,83 @@ func matchPOST(m MatcherService) func(echo.Context) error {
 		return c.JSON(http.StatusOK, result)
 	}
 }
+
+// reload starts reload of lookup data in the background. It requires the
+// AdminToken from the configuration, and is disabled if the token is empty.
+func reload(m MatcherService) func(echo.Context) error {
+	return func(c echo.Context) error {
+		if err := checkAdmin(m, c); err != nil {
+			return err
+		}
+
+		go func() {
+			err := m.Reload()
+			if errors.Is(err, gnmatcher.ErrBusy) {
+				slog.Warn("Cannot reload lookup data", "reason", err)
+				return
+			}
+			if err != nil {
+				slog.Error("Cannot reload lookup data", "error", err)
+			}
+		}()
+		slog.Info("Reload of lookup data is requested", "method", "POST")
+		return c.String(http.StatusAccepted, "Reload of lookup data started\n")
+	}
+}
+
+// updateInput is the body of the update request.
+type updateInput struct {
+	// DeltaDir is a directory on the server with new names.
+	DeltaDir string `json:"deltaDir"`
+}
+
+// updateOutput is the result of the update request.
+type updateOutput struct {
+	// NewStemsNum is the number of added stems.
+	NewStemsNum int `json:"newStemsNum"`
+}
+
+// update adds names from a delta located on the server to the lookup
+// data. It requires the AdminToken, like reload.
+func update(m MatcherService) func(echo.Context) error {
+	return func(c echo.Context) error {
+		if err := checkAdmin(m, c); err != nil {
+			return err
+		}
+		var inp updateInput
+		if err := c.Bind(&inp); err != nil {
+			return err
+		}
+		if inp.DeltaDir == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "deltaDir is empty")
+		}
+
+		num, err := m.Update(inp.DeltaDir)
+		if errors.Is(err, gnmatcher.ErrBusy) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if err != nil {
+			slog.Error("Cannot update lookup data", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		slog.Info("Lookup data are updated",
+			"delta", inp.DeltaDir,
+			"newStemsNum", num,
+			"method", "POST")
+		return c.JSON(http.StatusOK, updateOutput{NewStemsNum: num})
+	}
+}
+
+// checkAdmin returns an error if administrative endpoints are disabled,
+// or if the request does not have the correct AdminToken.
+func checkAdmin(m MatcherService, c echo.Context) error {
+	token := m.GetConfig().AdminToken
+	if token == "" {
+		return echo.ErrNotFound
+	}
+	auth := []byte(c.Request().Header.Get("Authorization"))
+	if subtle.ConstantTimeCompare(auth, []byte("Bearer "+token)) != 1 {
+		return echo.ErrUnauthorized
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package trie
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dvirsky/levenshtein"
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+)
+
+// deltaFile keeps stems added after the trie was built. They are used for
+// a small supplementary trie, because the main trie cannot be extended.
+const deltaFile = "delta_stems.txt"
+
+func (fm *fuzzyMatcher) AddStems(data provider.DataProvider) (int, error) {
+	var added []string
+	err := groupStems(data, func(stem string, items []mlib.MatchItem) error {
+		if err := updateKeyVal(fm.kvStems, stem, items); err != nil {
+			return err
+		}
+		if err := updateEpithets(fm.kvEpithets, stem, items); err != nil {
+			return err
+		}
+		if !fm.MatchStemExact(stem) {
+			added = append(added, stem)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Cannot add stems to key-value store", "error", err)
+		return 0, err
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+
+	fm.deltaMux.RLock()
+	stems := slices.Concat(fm.deltaStems, added)
+	fm.deltaMux.RUnlock()
+	slices.Sort(stems)
+	stems = slices.Compact(stems)
+
+	trie, err := levenshtein.NewMinTree(stems)
+	if err != nil {
+		return 0, err
+	}
+	err = saveDelta(fm.cfg.TrieDir(), stems)
+	if err != nil {
+		return 0, err
+	}
+
+	fm.deltaMux.Lock()
+	fm.deltaStems = stems
+	fm.deltaTrie = trie
+	fm.deltaMux.Unlock()
+	slog.Info("Added stems to supplementary trie", "stemsNum", len(added))
+	return len(added), nil
+}
+
+// loadDelta creates supplementary trie from stems that were added after
+// the main trie was built. If there are no such stems, the trie is nil.
+func loadDelta(triePath string) ([]string, *levenshtein.MinTree, error) {
+	f, err := os.Open(filepath.Join(triePath, deltaFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var stems []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if stem := strings.TrimSpace(sc.Text()); stem != "" {
+			stems = append(stems, stem)
+		}
+	}
+	if err = sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(stems) == 0 {
+		return nil, nil, nil
+	}
+
+	trie, err := levenshtein.NewMinTree(stems)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stems, trie, nil
+}
+
+// saveDelta saves sorted stems of the supplementary trie to the cache.
+func saveDelta(triePath string, stems []string) error {
+	path := filepath.Join(triePath, deltaFile)
+	tmp := path + ".tmp"
+	err := os.WriteFile(tmp, []byte(strings.Join(stems, "\n")+"\n"), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,165 @@
+package trie
+
+import (
+	"bytes"
+	"encoding/gob"
+	"log/slog"
+	"maps"
+
+	"github.com/dgraph-io/badger/v2"
+	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/io/progress"
+	"github.com/gnames/gnsys"
+)
+
+// initEpithetsKV creates key-value store of stemmed specific epithets and
+// canonical forms with these epithets. Keys consist of the epithet and
+// the ID of a canonical form separated by a space, so canonical forms of
+// an epithet are found by a prefix search, and the store can be built
+// from stems sorted in any order. It returns the number of canonical forms
+// added to the store.
+func initEpithetsKV(path string, data provider.DataProvider) (int, error) {
+	var err error
+	err = gnsys.MakeDir(path)
+	if err != nil {
+		slog.Error("Cannot create dir", "path", path, "error", err)
+		return 0, err
+	}
+
+	if keyValExists(path) {
+		slog.Info("Epithets key-value store already exists, skipping")
+		return 0, nil
+	}
+	kv, err := connectKeyVal(path)
+	if err != nil {
+		return 0, err
+	}
+	defer kv.Close()
+
+	slog.Info("Setting Epithets Key-Value store")
+	total, err := data.StemsNum()
+	if err != nil {
+		return 0, err
+	}
+	p := progress.New("epithets-kv", total)
+	kvTxn := kv.NewTransaction(true)
+	var count, num int
+	err = groupStems(data, func(stem string, items []mlib.MatchItem) error {
+		p.Inc()
+		epithet := fuzzy.SpecificEpithet(stem)
+		if epithet == "" {
+			return nil
+		}
+		for _, v := range items {
+			if err := setEpithet(kvTxn, epithet, v); err != nil {
+				return err
+			}
+			num++
+			count++
+		}
+		if count > 10_000 {
+			err := kvTxn.Commit()
+			if err != nil {
+				slog.Error("Transaction commit faied", "error", err)
+				return err
+			}
+			count = 0
+			kvTxn = kv.NewTransaction(true)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Cannot get stems from data provider", "error", err)
+		kvTxn.Discard()
+		return 0, err
+	}
+
+	err = kvTxn.Commit()
+	if err != nil {
+		slog.Error("Cannot commit kay-value transaction", "error", err)
+		return 0, err
+	}
+	p.Finish()
+	return num, nil
+}
+
+// updateEpithets adds canonical forms of a stem to the epithets store.
+// Data-sources of already saved canonical forms are merged with the new
+// ones.
+func updateEpithets(kv *badger.DB, stem string, items []mlib.MatchItem) error {
+	epithet := fuzzy.SpecificEpithet(stem)
+	if epithet == "" {
+		return nil
+	}
+	return kv.Update(func(txn *badger.Txn) error {
+		for _, v := range items {
+			saved, err := txn.Get(epithetKey(epithet, v.ID))
+			switch {
+			case err == badger.ErrKeyNotFound:
+			case err != nil:
+				return err
+			default:
+				var mi mlib.MatchItem
+				err = saved.Value(func(val []byte) error {
+					return gob.NewDecoder(bytes.NewReader(val)).Decode(&mi)
+				})
+				if err != nil {
+					return err
+				}
+				v.DataSourcesMap = maps.Clone(v.DataSourcesMap)
+				maps.Copy(v.DataSourcesMap, mi.DataSourcesMap)
+			}
+			if err = setEpithet(txn, epithet, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// getEpithet returns canonical forms that have the given stemmed specific
+// epithet.
+func getEpithet(kv *badger.DB, epithet string) ([]mlib.MatchItem, error) {
+	var res []mlib.MatchItem
+	prefix := epithetKey(epithet, "")
+	err := kv.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var mi mlib.MatchItem
+			err := it.Item().Value(func(val []byte) error {
+				return gob.NewDecoder(bytes.NewReader(val)).Decode(&mi)
+			})
+			if err != nil {
+				return err
+			}
+			res = append(res, mi)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Cannot get epithet from key-value store",
+			"epithet", epithet, "error", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func setEpithet(kvTxn *badger.Txn, epithet string, mi mlib.MatchItem) error {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(mi); err != nil {
+		slog.Error("Cannot marshal canonical", "error", err)
+		return err
+	}
+	if err := kvTxn.Set(epithetKey(epithet, mi.ID), b.Bytes()); err != nil {
+		slog.Error("Transaction failed to set key", "error", err)
+		return err
+	}
+	return nil
+}
+
+func epithetKey(epithet, canonicalID string) []byte {
+	return []byte(epithet + " " + canonicalID)
+}
@@ -2,127 +2,165 @@ package trie
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/gob"
+	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/dgraph-io/badger/v2"
 	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/io/progress"
 	"github.com/gnames/gnsys"
 )
 
 // initStemsKV creates key-value store for stems and their canonical forms.
-func initStemsKV(path string, db *sql.DB) error {
+// It returns the number of stems added to the store.
+func initStemsKV(path string, data provider.DataProvider) (int, error) {
 	var err error
 	err = gnsys.MakeDir(path)
 	if err != nil {
 		slog.Error("Cannot create dir", "path", path, "error", err)
-		return err
+		return 0, err
 	}
 
 	if keyValExists(path) {
 		slog.Info("Stems key-value store already exists, skipping")
-		return nil
+		return 0, nil
 	}
 	kv, err := connectKeyVal(path)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer kv.Close()
 
-	q := `SELECT s.name as name_stem, c.name, c.id, nsi.data_source_id
-          FROM canonical_stems s
-            JOIN name_strings ns
-              ON ns.canonical_stem_id = s.id
-            JOIN canonicals c
-              ON ns.canonical_id = c.id
-            JOIN name_string_indices nsi
-              ON ns.id = nsi.name_string_id
-        GROUP BY c.name, c.id, s.name, nsi.data_source_id
-          ORDER BY name_stem`
-
-	rows, err := db.Query(q)
+	slog.Info("Setting Stems Key-Value store")
+	total, err := data.StemsNum()
 	if err != nil {
-		slog.Error("Cannot get stems from DB", "error", err)
-		return err
+		return 0, err
 	}
-	slog.Info("Setting Stems Key-Value store")
+	p := progress.New("stems-kv", total)
 	kvTxn := kv.NewTransaction(true)
-	var stemRes []mlib.MatchItem
-	var dsMap map[int]struct{}
-	var dsID int
-	var currentStem, currentID, currentName, stem, name, id string
 	count := 0
-	for rows.Next() {
-		if err = rows.Scan(&stem, &name, &id, &dsID); err != nil {
-			slog.Error("Cannot read stem data from query", "error", err)
+	err = groupStems(data, func(stem string, items []mlib.MatchItem) error {
+		if err := setKeyVal(kvTxn, stem, items); err != nil {
 			return err
 		}
-		if currentStem == "" {
-			currentStem = stem
-		}
-		if currentID == "" {
-			currentID = id
-			currentName = name
-			dsMap = make(map[int]struct{})
+		p.Inc()
+		count++
+		if count > 10_000 {
+			err := kvTxn.Commit()
+			if err != nil {
+				slog.Error("Transaction commit faied", "error", err)
+				return err
+			}
+			count = 0
+			kvTxn = kv.NewTransaction(true)
 		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Cannot get stems from data provider", "error", err)
+		kvTxn.Discard()
+		return 0, err
+	}
 
-		if stem != currentStem {
-			count += 1
-
-			stemRes = append(stemRes,
-				mlib.MatchItem{
-					ID:             currentID,
-					MatchStr:       currentName,
-					DataSourcesMap: dsMap,
-				})
-			setKeyVal(kvTxn, currentStem, stemRes)
-			if count > 10_000 {
-				err = kvTxn.Commit()
-				if err != nil {
-					slog.Error("Transaction commit faied", "error", err)
-					return err
-				}
-				count = 0
-				kvTxn = kv.NewTransaction(true)
+	err = kvTxn.Commit()
+	if err != nil {
+		slog.Error("Cannot commit kay-value transaction", "error", err)
+		return 0, err
+	}
+	p.Finish()
+	return p.Count(), nil
+}
+
+// groupStems reads stems with their canonical forms from the data
+// provider, and calls fn for every stem with match items of its canonical
+// forms. The data provider returns records sorted by stems and canonical
+// IDs.
+func groupStems(
+	data provider.DataProvider,
+	fn func(stem string, items []mlib.MatchItem) error,
+) error {
+	var stem string
+	var items []mlib.MatchItem
+	err := data.StemCanonicals(func(sc provider.StemCanonical) error {
+		if sc.Stem != stem && len(items) > 0 {
+			if err := fn(stem, items); err != nil {
+				return err
 			}
-			currentStem = stem
-			currentID = id
-			currentName = name
-			stemRes = nil
-			dsMap = make(map[int]struct{})
+			items = nil
 		}
+		stem = sc.Stem
 
-		if id != currentID {
-			stemRes = append(stemRes,
-				mlib.MatchItem{
-					ID:             currentID,
-					MatchStr:       currentName,
-					DataSourcesMap: dsMap,
-				})
-			currentID = id
-			currentName = name
-			dsMap = make(map[int]struct{})
+		l := len(items)
+		if l == 0 || items[l-1].ID != sc.CanonicalID {
+			items = append(items, mlib.MatchItem{
+				ID:             sc.CanonicalID,
+				MatchStr:       sc.Canonical,
+				DataSourcesMap: make(map[int]struct{}),
+			})
+			l++
 		}
-		dsMap[dsID] = struct{}{}
-
-	}
-	stemRes = append(stemRes,
-		mlib.MatchItem{
-			ID:             id,
-			MatchStr:       name,
-			DataSourcesMap: dsMap,
-		})
-	setKeyVal(kvTxn, currentStem, stemRes)
-	err = kvTxn.Commit()
+		items[l-1].DataSourcesMap[sc.DataSourceID] = struct{}{}
+		return nil
+	})
 	if err != nil {
-		slog.Error("Cannot commit kay-value transaction", "error", err)
 		return err
 	}
+	if len(items) > 0 {
+		return fn(stem, items)
+	}
 	return nil
 }
 
+// updateKeyVal merges match items with the items that are already saved
+// for the stem.
+func updateKeyVal(kv *badger.DB, stem string, items []mlib.MatchItem) error {
+	return kv.Update(func(txn *badger.Txn) error {
+		var saved []mlib.MatchItem
+		item, err := txn.Get([]byte(stem))
+		switch {
+		case err == badger.ErrKeyNotFound:
+		case err != nil:
+			return err
+		default:
+			err = item.Value(func(val []byte) error {
+				return gob.NewDecoder(bytes.NewReader(val)).Decode(&saved)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return setKeyVal(txn, stem, mergeItems(saved, items))
+	})
+}
+
+// mergeItems adds new canonical forms and data-sources to match items.
+// The result is sorted by IDs of canonical forms.
+func mergeItems(items, add []mlib.MatchItem) []mlib.MatchItem {
+	for _, v := range add {
+		idx := slices.IndexFunc(items, func(mi mlib.MatchItem) bool {
+			return mi.ID == v.ID
+		})
+		if idx == -1 {
+			items = append(items, v)
+			continue
+		}
+		if items[idx].DataSourcesMap == nil {
+			items[idx].DataSourcesMap = make(map[int]struct{})
+		}
+		maps.Copy(items[idx].DataSourcesMap, v.DataSourcesMap)
+	}
+	slices.SortFunc(items, func(a, b mlib.MatchItem) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return items
+}
+
 func setKeyVal(kvTxn *badger.Txn,
 	stem string,
 	stemRes []mlib.MatchItem,
@@ -185,6 +223,64 @@ func getValue(kv *badger.DB, key string) ([]byte, error) {
 	return res, nil
 }
 
+// getPrefix returns canonical forms of all stems that start with the
+// prefix. Keys of the store are sorted, so only stems with the prefix are
+// read.
+func getPrefix(kv *badger.DB, prefix string) ([]mlib.MatchItem, error) {
+	var res []mlib.MatchItem
+	pref := []byte(prefix)
+	err := kv.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Seek(pref); it.ValidForPrefix(pref); it.Next() {
+			var items []mlib.MatchItem
+			err := it.Item().Value(func(val []byte) error {
+				return gob.NewDecoder(bytes.NewReader(val)).Decode(&items)
+			})
+			if err != nil {
+				return err
+			}
+			res = append(res, items...)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Cannot get stems from key-value store",
+			"prefix", prefix, "error", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// countKeys opens a key-value store and returns the number of its keys.
+// It returns an error if the store is empty.
+func countKeys(path string) (int, error) {
+	kv, err := connectKeyVal(path)
+	if err != nil {
+		return 0, err
+	}
+	defer kv.Close()
+
+	var res int
+	err = kv.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			res++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if res == 0 {
+		return 0, fmt.Errorf("key-value store '%s' is empty", path)
+	}
+	return res, nil
+}
+
 // keyValExists checks if key-value store is set.
 func keyValExists(path string) bool {
 	files, err := os.ReadDir(path)
@@ -4,17 +4,20 @@ package trie
 
 import (
 	"bytes"
-	"database/sql"
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/dvirsky/levenshtein"
 	"github.com/gnames/gnfmt"
 	mlib "github.com/gnames/gnlib/ent/matcher"
 	"github.com/gnames/gnmatcher/internal/ent/fuzzy"
-	"github.com/gnames/gnmatcher/internal/io/dbase"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/io/progress"
 	"github.com/gnames/gnmatcher/pkg/config"
 	"github.com/gnames/gnsys"
 )
@@ -23,34 +26,110 @@ const trieFile = "stem.trie"
 
 type fuzzyMatcher struct {
 	cfg     config.Config
+	data    provider.DataProvider
 	trie    *levenshtein.MinTree
 	kvStems *badger.DB
 	encoder gnfmt.Encoder
+
+	// kvEpithets keeps canonical forms by their stemmed specific epithets.
+	kvEpithets *badger.DB
+
+	// deltaTrie is a supplementary trie for stems added by AddStems.
+	deltaTrie  *levenshtein.MinTree
+	deltaStems []string
+	deltaMux   sync.RWMutex
 }
 
-// New takes configuration and returns back FuzzyMatcher object
-// responsible for fuzzy-matching strings to canonical forms of scientific
-// names.
-func New(cfg config.Config) fuzzy.FuzzyMatcher {
-	fm := fuzzyMatcher{cfg: cfg, encoder: gnfmt.GNgob{}}
+// New takes configuration and a provider of lookup data, and returns back
+// FuzzyMatcher object responsible for fuzzy-matching strings to canonical
+// forms of scientific names. The data are used only if the cache is empty.
+func New(cfg config.Config, data provider.DataProvider) fuzzy.FuzzyMatcher {
+	fm := fuzzyMatcher{cfg: cfg, data: data, encoder: gnfmt.GNgob{}}
 	return &fm
 }
 
+// BuildTrie creates a trie of stems from the data provider and saves it to
+// the cache, replacing the trie that might be there already. Stems added
+// to the supplementary trie are discarded. It returns the number of stems
+// in the trie.
+func BuildTrie(cfg config.Config, data provider.DataProvider) (int, error) {
+	err := gnsys.MakeDir(cfg.TrieDir())
+	if err != nil {
+		return 0, err
+	}
+	err = os.RemoveAll(filepath.Join(cfg.TrieDir(), deltaFile))
+	if err != nil {
+		return 0, err
+	}
+	_, num, err := populateAndSaveTrie(data, cfg.TrieDir())
+	return num, err
+}
+
+// BuildStemsKV creates key-value store of stems and their canonical forms
+// from the data provider. Already existing store is removed. It returns
+// the number of stems in the store.
+func BuildStemsKV(cfg config.Config, data provider.DataProvider) (int, error) {
+	err := os.RemoveAll(cfg.StemsDir())
+	if err != nil {
+		return 0, err
+	}
+	return initStemsKV(cfg.StemsDir(), data)
+}
+
+// BuildEpithetsKV creates key-value store of stemmed specific epithets and
+// their canonical forms from the data provider. Already existing store is
+// removed. It returns the number of canonical forms in the store.
+func BuildEpithetsKV(
+	cfg config.Config,
+	data provider.DataProvider,
+) (int, error) {
+	err := os.RemoveAll(cfg.EpithetsDir())
+	if err != nil {
+		return 0, err
+	}
+	return initEpithetsKV(cfg.EpithetsDir(), data)
+}
+
+// CheckTrie loads the trie from the cache to make sure it is complete. It
+// returns the number of stems in the trie.
+func CheckTrie(cfg config.Config) (int, error) {
+	trie, err := getCachedTrie(cfg.TrieDir())
+	if err != nil {
+		return 0, err
+	}
+	if trie.Root == nil || trie.Root.Number == 0 {
+		return 0, errors.New("trie is empty")
+	}
+	return trie.Root.Number, nil
+}
+
+// CheckStemsKV opens the key-value store of stems and returns the number of
+// stems in the store.
+func CheckStemsKV(cfg config.Config) (int, error) {
+	return countKeys(cfg.StemsDir())
+}
+
+// CheckEpithetsKV opens the key-value store of epithets and returns the
+// number of canonical forms in the store.
+func CheckEpithetsKV(cfg config.Config) (int, error) {
+	return countKeys(cfg.EpithetsDir())
+}
+
 func (fm *fuzzyMatcher) Init() error {
 	var err error
 	fm.prepareDirs()
-	db, err := dbase.NewDB(fm.cfg)
+
+	fm.trie, err = getTrie(fm.cfg.TrieDir(), fm.data)
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
-	fm.trie, err = getTrie(fm.cfg.TrieDir(), db)
+	fm.deltaStems, fm.deltaTrie, err = loadDelta(fm.cfg.TrieDir())
 	if err != nil {
 		return err
 	}
 
-	err = initStemsKV(fm.cfg.StemsDir(), db)
+	_, err = initStemsKV(fm.cfg.StemsDir(), fm.data)
 	if err != nil {
 		return err
 	}
@@ -60,21 +139,58 @@ func (fm *fuzzyMatcher) Init() error {
 		return err
 	}
 
+	_, err = initEpithetsKV(fm.cfg.EpithetsDir(), fm.data)
+	if err != nil {
+		return err
+	}
+
+	fm.kvEpithets, err = connectKeyVal(fm.cfg.EpithetsDir())
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// SetConfig updates configuration of the matcher.
-func (fm *fuzzyMatcher) SetConfig(cfg config.Config) {
-	fm.cfg = cfg
+// Close closes the stems and epithets key-value stores. The matcher cannot
+// be used after that.
+func (fm *fuzzyMatcher) Close() error {
+	var err error
+	if fm.kvStems != nil {
+		err = fm.kvStems.Close()
+	}
+	if fm.kvEpithets != nil {
+		err = errors.Join(err, fm.kvEpithets.Close())
+	}
+	return err
 }
 
-func (fm *fuzzyMatcher) MatchStem(stem string) []string {
-	return fm.trie.FuzzyMatches(stem, fm.cfg.MaxEditDist)
+func (fm *fuzzyMatcher) MatchStem(stem string, opts fuzzy.Options) []string {
+	res := fm.trie.FuzzyMatches(stem, opts.MaxEditDist)
+	fm.deltaMux.RLock()
+	defer fm.deltaMux.RUnlock()
+	if fm.deltaTrie == nil {
+		return res
+	}
+	for _, v := range fm.deltaTrie.FuzzyMatches(stem, opts.MaxEditDist) {
+		if !slices.Contains(res, v) {
+			res = append(res, v)
+		}
+	}
+	return res
 }
 
 func (fm *fuzzyMatcher) MatchStemExact(stem string) bool {
 	matches := fm.trie.FuzzyMatches(stem, 0)
-	return len(matches) > 0
+	if len(matches) > 0 {
+		return true
+	}
+	fm.deltaMux.RLock()
+	defer fm.deltaMux.RUnlock()
+	if fm.deltaTrie == nil {
+		return false
+	}
+	return len(fm.deltaTrie.FuzzyMatches(stem, 0)) > 0
 }
 
 func (fm *fuzzyMatcher) StemToMatchItems(
@@ -95,10 +211,25 @@ func (fm *fuzzyMatcher) StemToMatchItems(
 	return res, nil
 }
 
+func (fm *fuzzyMatcher) EpithetToMatchItems(
+	epithet string,
+) ([]mlib.MatchItem, error) {
+	return getEpithet(fm.kvEpithets, epithet)
+}
+
+func (fm *fuzzyMatcher) SpeciesToMatchItems(
+	species string,
+) ([]mlib.MatchItem, error) {
+	return getPrefix(fm.kvStems, species+" ")
+}
+
 // getTrie generates an in-memory trie for levenshtein automata. Such tree
-// can either be constructed from database or from a dump file. The tree
-// consists stemmed canonical forms of _gnames_ database.
-func getTrie(triePath string, db *sql.DB) (*levenshtein.MinTree, error) {
+// can either be constructed from the data provider or from a dump file. The
+// tree consists stemmed canonical forms of _gnames_ database.
+func getTrie(
+	triePath string,
+	data provider.DataProvider,
+) (*levenshtein.MinTree, error) {
 	var trie *levenshtein.MinTree
 	trie, err := getCachedTrie(triePath)
 	if err == nil {
@@ -106,24 +237,14 @@ func getTrie(triePath string, db *sql.DB) (*levenshtein.MinTree, error) {
 		return trie, nil
 	}
 
-	trie, err = populateAndSaveTrie(db, triePath)
+	trie, _, err = populateAndSaveTrie(data, triePath)
 	if err != nil {
-		slog.Error("Cannot build trie from db", "error", err)
+		slog.Error("Cannot build trie from data provider", "error", err)
 		return nil, err
 	}
 	return trie, nil
 }
 
-func getTrieSize(db *sql.DB) (int, error) {
-	q := "SELECT count(*) from canonical_stems"
-	var num int
-	row := db.QueryRow(q)
-	if err := row.Scan(&num); err != nil {
-		return 0, err
-	}
-	return num, nil
-}
-
 func getCachedTrie(triePath string) (*levenshtein.MinTree, error) {
 	var trie *levenshtein.MinTree
 	path := filepath.Join(triePath, trieFile)
@@ -131,48 +252,54 @@ func getCachedTrie(triePath string) (*levenshtein.MinTree, error) {
 	if err != nil {
 		return trie, err
 	}
+	defer trieFile.Close()
 	return levenshtein.LoadMinTree(trieFile)
 }
 
-func populateAndSaveTrie(db *sql.DB, triePath string) (*levenshtein.MinTree, error) {
-	slog.Info("Getting trie data from database")
+func populateAndSaveTrie(
+	data provider.DataProvider,
+	triePath string,
+) (*levenshtein.MinTree, int, error) {
+	slog.Info("Getting trie data from data provider")
 	var trie *levenshtein.MinTree
-	size, err := getTrieSize(db)
+	size, err := data.StemsNum()
 	if err != nil {
-		return trie, err
+		return trie, 0, err
 	}
-	names := make([]string, size)
+	names := make([]string, 0, size)
 
-	var name string
-	q := "SELECT name FROM canonical_stems order by name"
-	rows, err := db.Query(q)
+	p := progress.New("trie", size)
+	err = data.Stems(func(stem provider.Stem) error {
+		names = append(names, stem.Name)
+		p.Inc()
+		return nil
+	})
 	if err != nil {
-		return trie, err
+		return trie, 0, err
 	}
+	p.Finish()
 
-	for rows.Next() {
-		if err = rows.Scan(&name); err != nil {
-			return trie, err
-		}
-		names = append(names, name)
-	}
 	slog.Info("Building trie and saving it to disk")
 	path := filepath.Join(triePath, trieFile)
 	w, err := os.Create(path)
 	if err != nil {
-		return trie, err
+		return trie, 0, err
 	}
+	defer w.Close()
+
 	trie, err = levenshtein.NewMinTreeWrite(names, w)
 	if err != nil {
-		return trie, err
+		return trie, 0, err
 	}
 	slog.Info("Trie is created")
-	return trie, nil
+	return trie, len(names), nil
 }
 
-func (fm fuzzyMatcher) prepareDirs() {
+func (fm *fuzzyMatcher) prepareDirs() {
 	slog.Info("Preparing dirs for trie and stems key-value store")
-	dirs := []string{fm.cfg.TrieDir(), fm.cfg.StemsDir()}
+	dirs := []string{
+		fm.cfg.TrieDir(), fm.cfg.StemsDir(), fm.cfg.EpithetsDir(),
+	}
 	for _, dir := range dirs {
 		err := gnsys.MakeDir(dir)
 		if err != nil {
@@ -9,7 +9,8 @@ import (
 	"github.com/gnames/gnfmt"
 	mlib "github.com/gnames/gnlib/ent/matcher"
 	vlib "github.com/gnames/gnlib/ent/verifier"
-	"github.com/gnames/gnmatcher/internal/io/dbase"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
+	"github.com/gnames/gnmatcher/internal/io/progress"
 )
 
 func (v *virusio) prepareData() error {
@@ -24,27 +25,38 @@ func (v *virusio) prepareData() error {
 	err = v.dataFromCache(path)
 	if err != nil {
 		slog.Info("Cache for viruses is empty.", "path", path)
-		slog.Info("Virus data will be received from the database.")
+		slog.Info("Virus data will be received from the data provider.")
 	}
 
 	if v.sufary != nil {
 		return nil
 	}
 
-	var data []mlib.MatchItem
-	data, err = v.dataFromDB(path)
+	_, err = v.buildData()
+	return err
+}
+
+// buildData gets viruses from the data provider, creates suffix array
+// for them, and saves the data to the cache. It returns the number of
+// saved viruses.
+func (v *virusio) buildData() (int, error) {
+	data, err := v.dataFromProvider()
 	if err != nil {
-		slog.Error("Cannot create filters at %s from database.", "path", path)
-		return err
+		slog.Error("Cannot get virus data from data provider", "error", err)
+		return 0, err
 	}
 	bs := v.processData(data)
 	err = v.saveData(bs)
 	if err != nil {
-		slog.Error("Cannot save virus data to disk.", "path", path, "error", err)
-		return err
+		slog.Error(
+			"Cannot save virus data to disk.",
+			"path", v.cfg.VirusDir(),
+			"error", err,
+		)
+		return 0, err
 	}
 	slog.Info("Finished saving Virus data.")
-	return nil
+	return len(data), nil
 }
 
 func (v *virusio) saveData(bs []byte) error {
@@ -85,41 +97,22 @@ func (v *virusio) processData(data []mlib.MatchItem) []byte {
 	return names
 }
 
-func (v *virusio) dataFromDB(path string) ([]mlib.MatchItem, error) {
+func (v *virusio) dataFromProvider() ([]mlib.MatchItem, error) {
 	var res []mlib.MatchItem
-	db, err := dbase.NewDB(v.cfg)
-	if err != nil {
-		return nil, err
-	}
 	slog.Info("Importing lookup data for viruses")
 
-	q := `SELECT name_string_id, name, ds.id
-  FROM verification v
-    JOIN data_sources ds ON ds.id = v.data_source_id
-  WHERE virus='true'
-  ORDER by ds.is_curated desc`
-
-	rows, err := db.Query(q)
-	if err != nil {
-		return nil, err
-	}
-	slog.Info("Setting Viruses Key-Value store")
-
-	var uuid, name, currentID, currentName string
-	var dsID int
+	var currentID, currentName string
 	var dsMap map[int]struct{}
-	for rows.Next() {
-		if err = rows.Scan(&uuid, &name, &dsID); err != nil {
-			return nil, err
-		}
-
+	p := progress.New("virus", 0)
+	err := v.data.Viruses(func(vr provider.Virus) error {
+		p.Inc()
 		if currentID == "" {
-			currentID = uuid
-			currentName = name
+			currentID = vr.ID
+			currentName = vr.Name
 			dsMap = make(map[int]struct{})
 		}
 
-		if uuid != currentID {
+		if vr.ID != currentID {
 			res = append(res,
 				mlib.MatchItem{
 					ID:             currentID,
@@ -127,18 +120,28 @@ func (v *virusio) dataFromDB(path string) ([]mlib.MatchItem, error) {
 					MatchType:      vlib.Virus,
 					DataSourcesMap: dsMap,
 				})
-			currentID = uuid
-			currentName = name
+			currentID = vr.ID
+			currentName = vr.Name
 			dsMap = make(map[int]struct{})
 		}
-		dsMap[dsID] = struct{}{}
+		dsMap[vr.DataSourceID] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if currentID != "" {
+		res = append(res,
+			mlib.MatchItem{
+				ID:             currentID,
+				MatchStr:       currentName,
+				MatchType:      vlib.Virus,
+				DataSourcesMap: dsMap,
+			})
 	}
-	res = append(res,
-		mlib.MatchItem{ID: uuid,
-			MatchStr:  name,
-			MatchType: vlib.Virus,
-		})
-	return res, err
+	p.Finish()
+	return res, nil
 }
 
 func (v *virusio) dataFromCache(path string) error {
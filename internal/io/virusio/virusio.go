@@ -1,12 +1,16 @@
 package virusio
 
 import (
+	"cmp"
 	"fmt"
 	"index/suffixarray"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 
 	mlib "github.com/gnames/gnlib/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
 	"github.com/gnames/gnmatcher/internal/ent/virus"
 	"github.com/gnames/gnmatcher/pkg/config"
 	"github.com/gnames/gnsys"
@@ -14,18 +18,63 @@ import (
 
 type virusio struct {
 	cfg           config.Config
+	data          provider.DataProvider
 	sufary        *suffixarray.Index
 	mapMatchItems map[int]mlib.MatchItem
+
+	// starts are sorted offsets of names in the suffix array data, they
+	// are used to find a name by an offset of its substring.
+	starts []int
 }
 
-func New(cfg config.Config) virus.VirusMatcher {
+const (
+	// pieceLen is the number of runes at the start and at the end of a token
+	// that are used to find candidates for fuzzy matching.
+	pieceLen = 4
+
+	// maxPieceHits is the maximal number of hits of a piece in the suffix
+	// array. Pieces with more hits, like 'viru', do not help to find
+	// candidates.
+	maxPieceHits = 1000
+
+	// maxFuzzyCandidates is the maximal number of candidates with the most
+	// hits that are compared with the input.
+	maxFuzzyCandidates = 200
+)
+
+// New takes configuration and a provider of lookup data and returns
+// VirusMatcher. The data are used only if the cache is empty.
+func New(cfg config.Config, data provider.DataProvider) virus.VirusMatcher {
 	res := virusio{
 		cfg:           cfg,
+		data:          data,
 		mapMatchItems: make(map[int]mlib.MatchItem),
 	}
 	return &res
 }
 
+// Build creates lookup data for viruses from the data provider and saves
+// them to the cache, replacing data that might be there already. It
+// returns the number of viruses in the lookup data.
+func Build(cfg config.Config, data provider.DataProvider) (int, error) {
+	v := &virusio{cfg: cfg, data: data}
+	err := v.prepareDir()
+	if err != nil {
+		return 0, err
+	}
+	return v.buildData()
+}
+
+// Check loads lookup data for viruses from the cache to make sure they
+// are complete. It returns the number of viruses in the data.
+func Check(cfg config.Config) (int, error) {
+	v := &virusio{cfg: cfg}
+	if err := v.dataFromCache(cfg.VirusDir()); err != nil {
+		return 0, err
+	}
+	return len(v.mapMatchItems), nil
+}
+
 func (v *virusio) Init() error {
 	err := v.prepareDir()
 	if err != nil {
@@ -36,14 +85,10 @@ func (v *virusio) Init() error {
 	if err != nil {
 		return err
 	}
+	v.starts = slices.Sorted(maps.Keys(v.mapMatchItems))
 	return nil
 }
 
-// SetConfig updates configuration of the matcher.
-func (v *virusio) SetConfig(cfg config.Config) {
-	v.cfg = cfg
-}
-
 func (v *virusio) MatchVirus(s string) ([]mlib.MatchItem, error) {
 	bs := v.NameToBytes(s)
 	idxs := v.sufary.Lookup(bs, 21)
@@ -63,6 +108,69 @@ func (v *virusio) MatchVirus(s string) ([]mlib.MatchItem, error) {
 	return res, nil
 }
 
+// MatchVirusFuzzy finds candidates in the suffix array by the first and
+// the last runes of every token of the input, so a misspelled token or
+// tokens in a different order still give hits. Candidates with the most
+// hits are compared with the input.
+func (v *virusio) MatchVirusFuzzy(s string) ([]mlib.MatchItem, error) {
+	hits := make(map[int]int)
+	for _, token := range virus.Tokens(s) {
+		found := make(map[int]struct{})
+		for _, piece := range tokenPieces(token) {
+			idxs := v.sufary.Lookup([]byte(piece), maxPieceHits+1)
+			if len(idxs) > maxPieceHits {
+				continue
+			}
+			for _, idx := range idxs {
+				found[v.nameStart(idx)] = struct{}{}
+			}
+		}
+		for start := range found {
+			hits[start]++
+		}
+	}
+
+	starts := slices.SortedFunc(maps.Keys(hits), func(a, b int) int {
+		return cmp.Or(cmp.Compare(hits[b], hits[a]), cmp.Compare(a, b))
+	})
+	if len(starts) > maxFuzzyCandidates {
+		starts = starts[:maxFuzzyCandidates]
+	}
+
+	var cands []virus.Candidate
+	for _, start := range starts {
+		item, ok := v.mapMatchItems[start]
+		if !ok {
+			continue
+		}
+		approx, ok := virus.Approximate(s, item.MatchStr)
+		if !ok {
+			continue
+		}
+		cands = append(cands, virus.Candidate{Item: item, Approx: approx})
+	}
+	return virus.Select(cands), nil
+}
+
+// nameStart returns the offset of the name that contains the given offset.
+func (v *virusio) nameStart(idx int) int {
+	i, found := slices.BinarySearch(v.starts, idx)
+	if found || i == 0 {
+		return idx
+	}
+	return v.starts[i-1]
+}
+
+// tokenPieces returns the first and the last runes of a token. Short tokens
+// are returned as they are.
+func tokenPieces(token string) []string {
+	rs := []rune(token)
+	if len(rs) <= pieceLen {
+		return []string{token}
+	}
+	return []string{string(rs[:pieceLen]), string(rs[len(rs)-pieceLen:])}
+}
+
 func (v *virusio) prepareDir() error {
 	slog.Info("Preparing directory for viruses")
 	bloomDir := v.cfg.VirusDir()
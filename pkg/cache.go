@@ -0,0 +1,243 @@
+package gnmatcher
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/gnames/gnmatcher/internal/io/bloom"
+	"github.com/gnames/gnmatcher/internal/io/dumpio"
+	"github.com/gnames/gnmatcher/internal/io/manifest"
+	"github.com/gnames/gnmatcher/internal/io/trie"
+	"github.com/gnames/gnmatcher/internal/io/virusio"
+	"github.com/gnames/gnmatcher/pkg/config"
+)
+
+// CacheComponent is a part of the lookup cache that can be built
+// separately from other parts.
+type CacheComponent string
+
+// Components of the lookup cache. Their values are the same as names of
+// their directories in the CacheDir.
+const (
+	// BloomCache contains bloom filters for exact matching of stems.
+	BloomCache CacheComponent = "bloom"
+
+	// TrieCache contains a trie of stems for fuzzy matching.
+	TrieCache CacheComponent = "trie"
+
+	// StemsCache is a key-value store with stems and their canonical forms.
+	StemsCache CacheComponent = "stems-kv"
+
+	// EpithetsCache is a key-value store with stemmed specific epithets
+	// and canonical forms that contain them.
+	EpithetsCache CacheComponent = "epithets-kv"
+
+	// VirusCache contains lookup data for viruses.
+	VirusCache CacheComponent = "virus"
+)
+
+// CacheComponents lists all components of the cache in the order they are
+// built.
+var CacheComponents = []CacheComponent{
+	BloomCache, TrieCache, StemsCache, EpithetsCache, VirusCache,
+}
+
+// BuildCache builds given components of the lookup cache from the data
+// source set by the configuration (gnames database or a local dump). If no
+// components are given, all of them are built. Existing data of the
+// components are replaced. The process stops at the first error.
+//
+// Every built component is recorded in the cache manifest. A component
+// is removed from the manifest before its build starts, so an interrupted
+// build is detected and repeated by Init.
+func BuildCache(cfg config.Config, components ...CacheComponent) error {
+	if len(components) == 0 {
+		components = CacheComponents
+	}
+	for _, v := range components {
+		if !slices.Contains(CacheComponents, v) {
+			return fmt.Errorf("unknown cache component '%s'", v)
+		}
+	}
+
+	err := os.MkdirAll(cfg.CacheDir, 0755)
+	if err != nil {
+		return err
+	}
+	mf, err := manifest.Load(cfg.CacheDir)
+	if err != nil || mf.FormatVersion != manifest.FormatVersion {
+		// the manifest is unreadable or outdated, start a new one.
+		mf = manifest.Manifest{
+			FormatVersion: manifest.FormatVersion,
+			Components:    make(map[string]manifest.Component),
+		}
+	}
+
+	data := newDataProvider(cfg)
+	src := sourceInfo(cfg)
+	builders := map[CacheComponent]func() (int, error){
+		BloomCache: func() (int, error) { return bloom.Build(cfg, data) },
+		TrieCache:  func() (int, error) { return trie.BuildTrie(cfg, data) },
+		StemsCache: func() (int, error) { return trie.BuildStemsKV(cfg, data) },
+		EpithetsCache: func() (int, error) {
+			return trie.BuildEpithetsKV(cfg, data)
+		},
+		VirusCache: func() (int, error) { return virusio.Build(cfg, data) },
+	}
+
+	for _, v := range CacheComponents {
+		if !slices.Contains(components, v) {
+			continue
+		}
+		slog.Info("Building cache component", "component", v)
+		delete(mf.Components, string(v))
+		if err = mf.Save(cfg.CacheDir); err != nil {
+			return fmt.Errorf("cannot save cache manifest: %w", err)
+		}
+
+		num, err := builders[v]()
+		if err != nil {
+			return fmt.Errorf("cannot build '%s' cache: %w", v, err)
+		}
+
+		dir := filepath.Join(cfg.CacheDir, string(v))
+		c, err := manifest.NewComponent(dir, Version, src, num, v.withChecksums())
+		if err != nil {
+			return fmt.Errorf("cannot describe '%s' cache: %w", v, err)
+		}
+		mf.Components[string(v)] = c
+		if err = mf.Save(cfg.CacheDir); err != nil {
+			return fmt.Errorf("cannot save cache manifest: %w", err)
+		}
+	}
+	return nil
+}
+
+// withChecksums tells if checksums of the component's files are kept in
+// the manifest. Badger changes files of key-value stores even when they
+// are only read, so checksums of the stores would not be useful.
+func (c CacheComponent) withChecksums() bool {
+	return c != StemsCache && c != EpithetsCache
+}
+
+// checkCache verifies components of the cache against the manifest and
+// rebuilds components that are missing, incomplete, modified, or built
+// from a different or outdated source. A cache without a manifest was
+// created by an older version of gnmatcher, its existing components are
+// adopted if they load correctly, and other components are built.
+func checkCache(cfg config.Config) error {
+	src := sourceInfo(cfg)
+	if !manifest.Exists(cfg.CacheDir) {
+		if err := adoptCache(cfg, src); err != nil {
+			slog.Warn("Cannot adopt existing cache", "error", err)
+		}
+	}
+	mf, err := manifest.Load(cfg.CacheDir)
+	if err != nil {
+		slog.Warn("Cannot read cache manifest", "error", err)
+	}
+
+	var stale []CacheComponent
+	for _, v := range CacheComponents {
+		err = mf.Check(cfg.CacheDir, string(v), src)
+		if err != nil {
+			slog.Warn(
+				"Cache component is not valid and will be rebuilt",
+				"component", v, "reason", err,
+			)
+			stale = append(stale, v)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return BuildCache(cfg, stale...)
+}
+
+// adoptCache creates a manifest for components of a cache that was built
+// before manifests existed. Every component is loaded to make sure it is
+// complete, and the trie and the key-value store of stems have to contain
+// all stems of the bloom filters. Components that fail the checks are not adopted, so
+// they are rebuilt. Adopted components are assumed to be built from the
+// current source.
+func adoptCache(cfg config.Config, src manifest.Source) error {
+	mf := manifest.Manifest{
+		FormatVersion: manifest.FormatVersion,
+		Components:    make(map[string]manifest.Component),
+	}
+
+	var stemsNum int
+	checkStemsNum := func(num int, err error) (int, error) {
+		if err == nil && num < stemsNum {
+			err = fmt.Errorf("%d stems out of %d", num, stemsNum)
+		}
+		return num, err
+	}
+	checkers := map[CacheComponent]func() (int, error){
+		BloomCache: func() (int, error) {
+			num, err := bloom.Check(cfg)
+			stemsNum = num
+			return num, err
+		},
+		TrieCache: func() (int, error) {
+			return checkStemsNum(trie.CheckTrie(cfg))
+		},
+		StemsCache: func() (int, error) {
+			return checkStemsNum(trie.CheckStemsKV(cfg))
+		},
+		EpithetsCache: func() (int, error) { return trie.CheckEpithetsKV(cfg) },
+		VirusCache:    func() (int, error) { return virusio.Check(cfg) },
+	}
+
+	for _, v := range CacheComponents {
+		dir := filepath.Join(cfg.CacheDir, string(v))
+		files, err := os.ReadDir(dir)
+		if err != nil || len(files) == 0 {
+			continue
+		}
+		num, err := checkers[v]()
+		if err != nil {
+			slog.Warn("Existing cache component is not adopted",
+				"component", v, "reason", err)
+			continue
+		}
+		c, err := manifest.NewComponent(dir, Version, src, num, v.withChecksums())
+		if err != nil {
+			return err
+		}
+		slog.Info("Adopting existing cache component", "component", v)
+		mf.Components[string(v)] = c
+	}
+	if len(mf.Components) == 0 {
+		return nil
+	}
+	return mf.Save(cfg.CacheDir)
+}
+
+// sourceInfo describes the source of data for the cache. For a dump the
+// time of its latest modification is used to find out if the cache is
+// outdated. There is no cheap way to get such time from the database.
+func sourceInfo(cfg config.Config) manifest.Source {
+	if cfg.DumpDir == "" {
+		return manifest.Source{Name: "postgres://" + cfg.PgHost + "/" + cfg.PgDB}
+	}
+
+	res := manifest.Source{Name: "dump:" + cfg.DumpDir}
+	files := []string{
+		dumpio.StemsFile, dumpio.CanonicalsFile,
+		dumpio.IndicesFile, dumpio.VirusesFile,
+	}
+	for _, v := range files {
+		info, err := os.Stat(filepath.Join(cfg.DumpDir, v))
+		if err != nil {
+			continue
+		}
+		if mt := info.ModTime().UTC(); mt.After(res.UpdatedAt) {
+			res.UpdatedAt = mt
+		}
+	}
+	return res
+}
@@ -0,0 +1,245 @@
+package gnmatcher_test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/io/manifest"
+	gnmatcher "github.com/gnames/gnmatcher/pkg"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const dumpDir = "../internal/io/dumpio/testdata"
+
+func TestBuildCache(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(
+		config.OptCacheDir(t.TempDir()),
+		config.OptDumpDir(dumpDir),
+	)
+	err := gnmatcher.BuildCache(cfg, gnmatcher.TrieCache)
+	assert.Nil(err)
+	_, err = os.Stat(filepath.Join(cfg.TrieDir(), "stem.trie"))
+	assert.Nil(err)
+	_, err = os.Stat(cfg.StemsDir())
+	assert.True(os.IsNotExist(err))
+
+	err = gnmatcher.BuildCache(cfg)
+	assert.Nil(err)
+	for _, v := range []string{cfg.FiltersDir(), cfg.StemsDir(), cfg.VirusDir()} {
+		files, err := os.ReadDir(v)
+		assert.Nil(err)
+		assert.Greater(len(files), 0)
+	}
+
+	err = gnmatcher.BuildCache(cfg, "unknown")
+	assert.NotNil(err)
+}
+
+// TestInitRebuildsCache checks that Init rebuilds cache components that
+// were modified or are missing from the manifest.
+func TestInitRebuildsCache(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(
+		config.OptCacheDir(t.TempDir()),
+		config.OptDumpDir(dumpDir),
+	)
+	err := gnmatcher.BuildCache(cfg)
+	assert.Nil(err)
+
+	mf, err := manifest.Load(cfg.CacheDir)
+	assert.Nil(err)
+	assert.Equal(4, mf.Components["trie"].RecordsNum)
+	assert.Equal(4, mf.Components["stems-kv"].RecordsNum)
+	assert.Equal(4, mf.Components["epithets-kv"].RecordsNum)
+	assert.Equal(4, mf.Components["trie"].RecordsNum)
+	assert.Equal(2, mf.Components["virus"].RecordsNum)
+	src := mf.Components["bloom"].Source
+	for _, v := range gnmatcher.CacheComponents {
+		assert.Nil(mf.Check(cfg.CacheDir, string(v), src))
+	}
+
+	trieFile := filepath.Join(cfg.TrieDir(), "stem.trie")
+	err = os.WriteFile(trieFile, []byte("corrupted"), 0644)
+	assert.Nil(err)
+	delete(mf.Components, "virus")
+	assert.Nil(mf.Save(cfg.CacheDir))
+	assert.NotNil(mf.Check(cfg.CacheDir, "trie", src))
+
+	gnm := gnmatcher.New(cfg)
+	err = gnm.Init()
+	assert.Nil(err)
+
+	mf, err = manifest.Load(cfg.CacheDir)
+	assert.Nil(err)
+	for _, v := range gnmatcher.CacheComponents {
+		assert.Nil(mf.Check(cfg.CacheDir, string(v), src))
+	}
+	res := gnm.MatchNames([]string{
+		"Pomatomus saltatrix", "Tobacco mosaic virus", "Pomatemas saltatrix",
+	})
+	assert.Equal(vlib.Exact, res.Matches[0].MatchType)
+	assert.Equal(vlib.Virus, res.Matches[1].MatchType)
+	assert.Equal(vlib.Fuzzy, res.Matches[2].MatchType)
+	assert.Equal("Pomatomus saltatrix", res.Matches[2].MatchItems[0].MatchStr)
+}
+
+// TestInitAdoptsCache checks that Init adopts components of a cache
+// without a manifest, and builds only missing components.
+func TestInitAdoptsCache(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(
+		config.OptCacheDir(t.TempDir()),
+		config.OptDumpDir(dumpDir),
+	)
+	err := gnmatcher.BuildCache(cfg)
+	assert.Nil(err)
+	assert.Nil(os.Remove(filepath.Join(cfg.CacheDir, manifest.File)))
+	epithetsDir := filepath.Join(cfg.CacheDir, string(gnmatcher.EpithetsCache))
+	assert.Nil(os.RemoveAll(epithetsDir))
+
+	gnm := gnmatcher.New(cfg)
+	assert.Nil(gnm.Init())
+
+	mf, err := manifest.Load(cfg.CacheDir)
+	assert.Nil(err)
+	src := mf.Components["bloom"].Source
+	for _, v := range gnmatcher.CacheComponents {
+		assert.Nil(mf.Check(cfg.CacheDir, string(v), src))
+	}
+	assert.Equal(4, mf.Components["bloom"].RecordsNum)
+	assert.Equal(2, mf.Components["virus"].RecordsNum)
+	assert.Equal(4, mf.Components["epithets-kv"].RecordsNum)
+
+	res := gnm.MatchNames([]string{"Pomatomus saltatrix"})
+	assert.Equal(vlib.Exact, res.Matches[0].MatchType)
+}
+
+// TestInitRejectsBrokenCache checks that components of a cache without a
+// manifest are rebuilt, if they are incomplete.
+func TestInitRejectsBrokenCache(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(
+		config.OptCacheDir(t.TempDir()),
+		config.OptDumpDir(dumpDir),
+	)
+	err := gnmatcher.BuildCache(cfg)
+	assert.Nil(err)
+	assert.Nil(os.Remove(filepath.Join(cfg.CacheDir, manifest.File)))
+
+	// a half-built key-value store of stems
+	kv, err := badger.Open(badger.DefaultOptions(cfg.StemsDir()).WithLogger(nil))
+	assert.Nil(err)
+	err = kv.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte("Bubo bub"))
+	})
+	assert.Nil(err)
+	assert.Nil(kv.Close())
+
+	// a truncated trie
+	triePath := filepath.Join(cfg.TrieDir(), "stem.trie")
+	assert.Nil(os.Truncate(triePath, 10))
+
+	gnm := gnmatcher.New(cfg)
+	assert.Nil(gnm.Init())
+	defer gnm.Close()
+
+	mf, err := manifest.Load(cfg.CacheDir)
+	assert.Nil(err)
+	assert.Equal(4, mf.Components["trie"].RecordsNum)
+	assert.Equal(4, mf.Components["stems-kv"].RecordsNum)
+
+	res := gnm.MatchNames([]string{"Bubo bubo", "Bubo bubu"})
+	assert.Equal(vlib.Exact, res.Matches[0].MatchType)
+	assert.Equal(vlib.Fuzzy, res.Matches[1].MatchType)
+}
+
+// TestCacheApproximateVirus checks fuzzy and token-set matching of viruses
+// with the suffix array from the cache.
+func TestCacheApproximateVirus(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(
+		config.OptCacheDir(t.TempDir()),
+		config.OptDumpDir(dumpDir),
+	)
+	gnm := gnmatcher.New(cfg)
+	assert.Nil(gnm.Init())
+
+	res := gnm.MatchNames([]string{
+		"Tobaco mosaic virus", "mosaic virus tobacco", "virus Antarctic 1",
+	})
+	for i, v := range []string{
+		"Tobacco mosaic virus", "Tobacco mosaic virus", "Antarctic virus 1",
+	} {
+		assert.Equal(vlib.Virus, res.Matches[i].MatchType)
+		assert.Equal(1, len(res.Matches[i].MatchItems))
+		assert.Equal(v, res.Matches[i].MatchItems[0].MatchStr)
+	}
+}
+
+// TestCacheSpeciesGroup checks that siblings of an infraspecific name are
+// found in the cache by its species.
+func TestCacheSpeciesGroup(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(
+		config.OptCacheDir(t.TempDir()),
+		config.OptDumpDir(dumpDir),
+	)
+	gnm := gnmatcher.New(cfg)
+	assert.Nil(gnm.Init())
+	defer gnm.Close()
+
+	deltaDir := t.TempDir()
+	files := map[string]string{
+		"canonicals.tsv": "name\tstem\tid\nBubo bubo bubo\tBubo bub bub\t\n",
+		"name_string_indices.tsv": "canonical_id\tdata_source_id\n" +
+			"bcf101e8-ff70-5a27-a40a-6bf58036934d\t1\n",
+	}
+	for k, v := range files {
+		err := os.WriteFile(filepath.Join(deltaDir, k), []byte(v), 0644)
+		assert.Nil(err)
+	}
+	num, err := gnm.Update(deltaDir)
+	assert.Nil(err)
+	assert.Equal(1, num)
+
+	res := gnm.MatchNames(
+		[]string{"Bubo bubo jakutensis", "Pardosa moesta major"},
+		config.OptWithSpeciesGroup(true),
+	)
+	match := res.Matches[0]
+	assert.Equal(vlib.ExactSpeciesGroup, match.MatchType)
+	var matchStrs []string
+	for _, v := range match.MatchItems {
+		matchStrs = append(matchStrs, v.MatchStr)
+	}
+	assert.ElementsMatch([]string{"Bubo bubo", "Bubo bubo bubo"}, matchStrs)
+
+	match = res.Matches[1]
+	assert.Equal(vlib.ExactSpeciesGroup, match.MatchType)
+	assert.Equal(1, len(match.MatchItems))
+	assert.Equal("Pardosa moesta", match.MatchItems[0].MatchStr)
+}
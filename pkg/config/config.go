@@ -5,12 +5,25 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/gnames/gnlib/ent/nomcode"
 	"github.com/gnames/gnsys"
 )
 
 // Config collects and stores external configuration data.
 type Config struct {
+	// AdminToken enables administrative endpoints of the REST service, for
+	// example reload of lookup data. Requests to such endpoints have to
+	// contain 'Authorization: Bearer <AdminToken>' header. If the token is
+	// empty, administrative endpoints are disabled.
+	AdminToken string
+
+	// BestItemsNum limits the number of match items of a name-string to the
+	// given number of items with the highest scores. If it is 0, all items
+	// are returned.
+	BestItemsNum int
+
 	// CacheDir is the main directory for gnmatcher files. It contains
 	// bloom filters levenshtein automata trees, key-value stores etc.
 	CacheDir string
@@ -23,7 +36,20 @@ type Config struct {
 	// partial match, finding 'Aus bus' as with a MatchType of PartialMatch.
 	DataSources []int
 
-	// JobsNum is the number of jobs to run in parallel
+	// DumpDir is a directory with a dump of gnames data in tab-separated
+	// files. If it is set, lookup caches are built from these files instead
+	// of gnames PostgreSQL database. The format of the files is described
+	// in the documentation of `internal/io/dumpio` package.
+	DumpDir string
+
+	// EditDistThresholds is a table of maximal edit distances for stems of
+	// different lengths and numbers of words. It is used if
+	// WithAdaptiveEditDist is true. If it is empty,
+	// DefaultEditDistThresholds are used.
+	EditDistThresholds []EditDistThreshold
+
+	// JobsNum is the number of workers that match names. The workers are
+	// created once and are shared by all matching requests.
 	JobsNum int
 
 	// MaxEditDist is the maximal allowed edit distance for levenshtein
@@ -31,6 +57,23 @@ type Config struct {
 	// execution slows down dramatically with the MaxEditDist > 1.
 	MaxEditDist int
 
+	// MinScore is the minimal score of returned match items. Items with
+	// lower scores are removed. Scores are in the range from 0 to 1.
+	MinScore float64
+
+	// NomCode is the nomenclatural code of matched names. It changes how
+	// names are parsed, for example cultivar epithets are kept only with
+	// the code of cultivated plants. It also changes partial matching, with
+	// botanical codes infraspecific epithets are not combined with the
+	// genus. If it is unknown, names are parsed by default rules.
+	NomCode nomcode.Code
+
+	// OCRConfusions is a table of character sequences that are often mistaken
+	// for each other by OCR, for example 'rn' and 'm'. Every confusion has
+	// a "from:to" format. It is used if WithOCRDistance is true. If it is
+	// empty, the default table is used.
+	OCRConfusions []string
+
 	// PgDB the database name where gnames data is located.
 	PgDB string
 
@@ -46,10 +89,41 @@ type Config struct {
 	// PgUser is the user for the database.
 	PgUser string
 
+	// QueueSize is the maximal number of names of one request that wait for
+	// a free worker. When the queue is full, the request waits until workers
+	// take some of its names.
+	QueueSize int
+
+	// WithAdaptiveEditDist is true when the maximal edit distance of fuzzy
+	// matching depends on the length and the number of words of a stem
+	// according to EditDistThresholds, instead of MaxEditDist. Only long
+	// names pay the cost of slower matching with edit distance 2.
+	WithAdaptiveEditDist bool
+
+	// WithAllPartials is true when partial matching does not stop at the
+	// first found truncated version of a name, and returns matches of all
+	// versions, including the genus.
+	WithAllPartials bool
+
+	// WithBatchContext is true when names of a request are used as the
+	// context for each other. Fuzzy and partial match items with a genus
+	// that is found in exact matches of the same request get higher scores.
+	// It is ignored by streams of names.
+	WithBatchContext bool
+
 	// WithSpeciesGroup is true when searching for "Aus bus" also searches for
-	// "Aus bus bus".
+	// autonyms "Aus bus bus" and "Aus bus bus bus", and searching for an
+	// infraspecific name, like "Aus bus cus", also searches for "Aus bus"
+	// and for other infraspecific taxa of "Aus bus".
 	WithSpeciesGroup bool
 
+	// WithRecombinations is true when names with a genus that is not found
+	// are matched by their epithets to canonical forms with any genus.
+	// Such matches show names that were moved to another genus. When it is
+	// false, only canonical forms with a genus similar to the input's one
+	// are returned by the epithet matching.
+	WithRecombinations bool
+
 	// WithUninomialFuzzyMatch is true when it is allowed to use fuzzy match for
 	// uninomial names.
 	WithUninomialFuzzyMatch bool
@@ -57,6 +131,66 @@ type Config struct {
 	// WithRelaxedFuzzyMatch is true when it is allowed to use relaxed fuzzy
 	// match.
 	WithRelaxedFuzzyMatch bool
+
+	// WithWordsChange is true when names that were not found by exact or
+	// fuzzy matching are matched with two adjacent words merged, or with a
+	// word split in two, for example 'Pomato mus saltatrix'. It also allows
+	// to match uninomials to binomials, like 'Homosapiens' to 'Homo sapiens'.
+	WithWordsChange bool
+
+	// WithOCRDistance is true when fuzzy matching uses weighted edit
+	// distance, where typical OCR confusions from OCRConfusions cost less
+	// than a regular edit. It helps to match names from digitized texts.
+	WithOCRDistance bool
+
+	// WithExplain is true when results of matching contain explanations:
+	// which matching stages were tried, which candidates they found or
+	// rejected and why, and how much time they took. It slows down matching
+	// and is intended for debugging of unexpected results.
+	WithExplain bool
+}
+
+// EditDistThreshold sets the maximal edit distance for stems that have
+// at least MinLength characters and at least MinWords words.
+type EditDistThreshold struct {
+	// MinLength is the minimal number of characters in a stem.
+	MinLength int
+
+	// MinWords is the minimal number of words in a stem.
+	MinWords int
+
+	// MaxEditDist is the maximal edit distance for such stems. It can be
+	// from 0 to 2.
+	MaxEditDist int
+}
+
+// DefaultEditDistThresholds allow edit distance 2 only for long trinomials
+// and for very long names.
+var DefaultEditDistThresholds = []EditDistThreshold{
+	{MinLength: 0, MaxEditDist: 1},
+	{MinLength: 21, MinWords: 3, MaxEditDist: 2},
+	{MinLength: 26, MaxEditDist: 2},
+}
+
+// MaxEditDistFor returns the maximal edit distance for a stem with the
+// given length and number of words. If adaptive edit distance is not
+// used, it returns MaxEditDist. Otherwise it returns the largest edit
+// distance of thresholds that fit the stem.
+func (cfg Config) MaxEditDistFor(length, wordsNum int) int {
+	if !cfg.WithAdaptiveEditDist {
+		return cfg.MaxEditDist
+	}
+	thresholds := cfg.EditDistThresholds
+	if len(thresholds) == 0 {
+		thresholds = DefaultEditDistThresholds
+	}
+	var res int
+	for _, v := range thresholds {
+		if length >= v.MinLength && wordsNum >= v.MinWords {
+			res = max(res, v.MaxEditDist)
+		}
+	}
+	return res
 }
 
 // TrieDir returns path where to dump/restore
@@ -77,6 +211,12 @@ func (cfg Config) StemsDir() string {
 	return filepath.Join(cfg.CacheDir, "stems-kv")
 }
 
+// EpithetsDir returns path where key-value store of specific epithets
+// is located.
+func (cfg Config) EpithetsDir() string {
+	return filepath.Join(cfg.CacheDir, "epithets-kv")
+}
+
 // VirusDir returns path to cache virus matching data.
 func (cfg Config) VirusDir() string {
 	return filepath.Join(cfg.CacheDir, "virus")
@@ -85,6 +225,26 @@ func (cfg Config) VirusDir() string {
 // Option is a type of all options for Config.
 type Option func(cfg *Config)
 
+// OptAdminToken sets a token for administrative endpoints of the REST
+// service.
+func OptAdminToken(s string) Option {
+	return func(cfg *Config) {
+		cfg.AdminToken = s
+	}
+}
+
+// OptBestItemsNum sets the maximal number of match items with the highest
+// scores returned for a name-string. If it is 0, all items are returned.
+func OptBestItemsNum(i int) Option {
+	return func(cfg *Config) {
+		if i < 0 {
+			slog.Warn("BestItemsNum cannot be negative, keeping it at 0")
+			return
+		}
+		cfg.BestItemsNum = i
+	}
+}
+
 // OptCacheDir sets a directory for key-value stores and temporary files.
 func OptCacheDir(s string) Option {
 	return func(cfg *Config) {
@@ -103,7 +263,38 @@ func OptDataSources(ints []int) Option {
 	}
 }
 
-// OptJobsNum sets the number of jobs to run in parallel
+// OptDumpDir sets a directory with a dump of gnames data. If it is set,
+// the dump is used instead of PostgreSQL database for building caches.
+func OptDumpDir(s string) Option {
+	return func(cfg *Config) {
+		dumpDir, err := gnsys.ConvertTilda(s)
+		if err != nil {
+			slog.Error("Cannot expand '~' in path", "path", s, "error", err)
+		}
+		cfg.DumpDir = dumpDir
+	}
+}
+
+// OptEditDistThresholds sets the table of maximal edit distances for
+// adaptive edit distance. Thresholds with edit distance outside of the
+// range from 0 to 2 are ignored.
+func OptEditDistThresholds(ts []EditDistThreshold) Option {
+	return func(cfg *Config) {
+		res := make([]EditDistThreshold, 0, len(ts))
+		for _, v := range ts {
+			if v.MaxEditDist < 0 || v.MaxEditDist > 2 {
+				slog.Warn("MaxEditDist of a threshold can be from 0 to 2, "+
+					"ignoring the threshold", "max-edit-dist", v.MaxEditDist)
+				continue
+			}
+			res = append(res, v)
+		}
+		cfg.EditDistThresholds = res
+	}
+}
+
+// OptJobsNum sets the number of workers that match names. It is used
+// when the matcher is created, and does not change anything per request.
 func OptJobsNum(i int) Option {
 	return func(cfg *Config) {
 		cfg.JobsNum = i
@@ -138,6 +329,52 @@ func OptWithUninomialFuzzyMatch(b bool) Option {
 	}
 }
 
+// OptMinScore sets the minimal score of returned match items.
+func OptMinScore(f float64) Option {
+	return func(cfg *Config) {
+		if f < 0 || f > 1 {
+			slog.Warn("MinScore has to be between 0 and 1, ignoring it",
+				"min-score", f)
+			return
+		}
+		cfg.MinScore = f
+	}
+}
+
+// OptNomCode sets the nomenclatural code of matched names. Only
+// bacterial, botanical, cultivated plants and zoological codes are
+// supported, other codes are ignored.
+func OptNomCode(c nomcode.Code) Option {
+	return func(cfg *Config) {
+		switch c {
+		case nomcode.Unknown, nomcode.Bacterial, nomcode.Botanical,
+			nomcode.Cultivars, nomcode.Zoological:
+			cfg.NomCode = c
+		default:
+			slog.Warn("Nomenclatural code is not supported, ignoring it",
+				"code", c.String())
+		}
+	}
+}
+
+// OptOCRConfusions sets a table of OCR confusions in "from:to" format.
+// Confusions in a wrong format are ignored.
+func OptOCRConfusions(ss []string) Option {
+	return func(cfg *Config) {
+		res := make([]string, 0, len(ss))
+		for _, v := range ss {
+			from, to, ok := strings.Cut(v, ":")
+			if !ok || from == "" || to == "" || from == to {
+				slog.Warn("OCR confusion is not in 'from:to' format, ignoring it",
+					"confusion", v)
+				continue
+			}
+			res = append(res, v)
+		}
+		cfg.OCRConfusions = res
+	}
+}
+
 // OptPgHost sets the host of gnames database
 func OptPgHost(s string) Option {
 	return func(cfg *Config) {
@@ -173,6 +410,63 @@ func OptPgDB(s string) Option {
 	}
 }
 
+// OptQueueSize sets the maximal number of names of one request waiting
+// for workers. It is used when the matcher is created, and does not change
+// anything per request.
+func OptQueueSize(i int) Option {
+	return func(cfg *Config) {
+		cfg.QueueSize = i
+	}
+}
+
+// OptWithAdaptiveEditDist sets an option that makes the maximal edit
+// distance of fuzzy matching to depend on the length of a stem.
+func OptWithAdaptiveEditDist(b bool) Option {
+	return func(cfg *Config) {
+		cfg.WithAdaptiveEditDist = b
+	}
+}
+
+// OptWithAllPartials sets an option to return matches of all truncated
+// versions of a name by partial matching.
+func OptWithAllPartials(b bool) Option {
+	return func(cfg *Config) {
+		cfg.WithAllPartials = b
+	}
+}
+
+// OptWithBatchContext sets an option that boosts scores of fuzzy and
+// partial match items with genera from exact matches of the same request.
+func OptWithBatchContext(b bool) Option {
+	return func(cfg *Config) {
+		cfg.WithBatchContext = b
+	}
+}
+
+// OptWithExplain sets an option that adds explanations of matching to the
+// detailed output.
+func OptWithExplain(b bool) Option {
+	return func(cfg *Config) {
+		cfg.WithExplain = b
+	}
+}
+
+// OptWithOCRDistance sets an option that makes fuzzy matching to use
+// weighted edit distance for OCR confusions.
+func OptWithOCRDistance(b bool) Option {
+	return func(cfg *Config) {
+		cfg.WithOCRDistance = b
+	}
+}
+
+// OptWithRecombinations sets an option that allows epithet matching to
+// return canonical forms with a genus that differs from the input's one.
+func OptWithRecombinations(b bool) Option {
+	return func(cfg *Config) {
+		cfg.WithRecombinations = b
+	}
+}
+
 // OptWithSpeciesGroup sets the WithSpeciesGroup field
 func OptWithSpeciesGroup(b bool) Option {
 	return func(cfg *Config) {
@@ -180,6 +474,14 @@ func OptWithSpeciesGroup(b bool) Option {
 	}
 }
 
+// OptWithWordsChange sets an option that allows matching of names with
+// merged or split words.
+func OptWithWordsChange(b bool) Option {
+	return func(cfg *Config) {
+		cfg.WithWordsChange = b
+	}
+}
+
 // New is a Config constructor that takes external options to
 // update default values to external ones.
 func New(opts ...Option) Config {
@@ -199,6 +501,7 @@ func New(opts ...Option) Config {
 		PgUser:      "postgres",
 		PgPass:      "postgres",
 		PgDB:        "gnames",
+		QueueSize:   100,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
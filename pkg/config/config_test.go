@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/gnames/gnlib/ent/nomcode"
 	"github.com/gnames/gnmatcher/pkg/config"
 	"github.com/stretchr/testify/assert"
 )
@@ -23,6 +24,7 @@ func TestNew(t *testing.T) {
 		PgUser:      "postgres",
 		PgPass:      "postgres",
 		PgDB:        "gnames",
+		QueueSize:   100,
 	}
 	assert.Equal(t, deflt, cfg)
 }
@@ -40,6 +42,7 @@ func TestNewOpts(t *testing.T) {
 		PgUser:      "gnm",
 		PgPass:      "secret",
 		PgDB:        "gnm",
+		QueueSize:   500,
 	}
 	assert.Equal(t, withOpts, cfg)
 }
@@ -59,11 +62,61 @@ func TestMaxED(t *testing.T) {
 	assert.Equal(t, 2, cfg.MaxEditDist)
 }
 
+// OCR confusions have to be in "from:to" format
+func TestOCRConfusions(t *testing.T) {
+	oldLevel := slog.SetLogLoggerLevel(10)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(config.OptOCRConfusions(
+		[]string{"rn:m", "cl", ":d", "a:a", "1:l"},
+	))
+	assert.Equal(t, []string{"rn:m", "1:l"}, cfg.OCRConfusions)
+}
+
+func TestEditDistThresholds(t *testing.T) {
+	oldLevel := slog.SetLogLoggerLevel(10)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	assert := assert.New(t)
+	cfg := config.New(config.OptMaxEditDist(2))
+	assert.Equal(2, cfg.MaxEditDistFor(5, 2))
+
+	cfg = config.New(config.OptWithAdaptiveEditDist(true))
+	assert.Equal(1, cfg.MaxEditDistFor(12, 2))
+	assert.Equal(1, cfg.MaxEditDistFor(22, 2))
+	assert.Equal(2, cfg.MaxEditDistFor(22, 3))
+	assert.Equal(2, cfg.MaxEditDistFor(26, 2))
+
+	cfg = config.New(
+		config.OptWithAdaptiveEditDist(true),
+		config.OptEditDistThresholds([]config.EditDistThreshold{
+			{MinLength: 8, MaxEditDist: 1},
+			{MinLength: 15, MaxEditDist: 2},
+			{MinLength: 30, MaxEditDist: 3},
+		}),
+	)
+	assert.Equal(2, len(cfg.EditDistThresholds))
+	assert.Equal(0, cfg.MaxEditDistFor(7, 2))
+	assert.Equal(1, cfg.MaxEditDistFor(8, 2))
+	assert.Equal(2, cfg.MaxEditDistFor(35, 2))
+}
+
+func TestNomCode(t *testing.T) {
+	oldLevel := slog.SetLogLoggerLevel(10)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(config.OptNomCode(nomcode.Botanical))
+	assert.Equal(t, nomcode.Botanical, cfg.NomCode)
+	cfg = config.New(config.OptNomCode(nomcode.Virus))
+	assert.Equal(t, nomcode.Unknown, cfg.NomCode)
+}
+
 func TestHelpers(t *testing.T) {
 	cfg := config.New()
 	assert.Contains(t, cfg.TrieDir(), "/gnmatcher/trie")
 	assert.Contains(t, cfg.FiltersDir(), "/gnmatcher/bloom")
 	assert.Contains(t, cfg.StemsDir(), "/gnmatcher/stems-kv")
+	assert.Contains(t, cfg.EpithetsDir(), "/gnmatcher/epithets-kv")
 }
 
 func opts() []config.Option {
@@ -76,5 +129,6 @@ func opts() []config.Option {
 		config.OptPgPass("secret"),
 		config.OptPgPort(1234),
 		config.OptPgDB("gnm"),
+		config.OptQueueSize(500),
 	}
 }
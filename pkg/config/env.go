@@ -21,11 +21,13 @@ func strOpts() []Option {
 	var res []Option
 
 	envToOpt := map[string]func(string) Option{
-		"GNM_PG_HOST":   OptPgHost,
-		"GNM_PG_USER":   OptPgUser,
-		"GNM_PG_PASS":   OptPgPass,
-		"GNM_PG_DB":     OptPgDB,
-		"GNM_CACHE_DIR": OptCacheDir,
+		"GNM_PG_HOST":     OptPgHost,
+		"GNM_PG_USER":     OptPgUser,
+		"GNM_PG_PASS":     OptPgPass,
+		"GNM_PG_DB":       OptPgDB,
+		"GNM_CACHE_DIR":   OptCacheDir,
+		"GNM_DUMP_DIR":    OptDumpDir,
+		"GNM_ADMIN_TOKEN": OptAdminToken,
 	}
 
 	for envVar, optFunc := range envToOpt {
@@ -44,6 +46,7 @@ func intOpts() []Option {
 		"GNM_PG_PORT":       OptPgPort,
 		"GNM_JOBS_NUM":      OptJobsNum,
 		"GNM_MAX_EDIT_DIST": OptMaxEditDist,
+		"GNM_QUEUE_SIZE":    OptQueueSize,
 	}
 	for envVar, optFunc := range envToOpt {
 		if envVar == "" {
@@ -0,0 +1,53 @@
+package gnmatcher
+
+import (
+	"github.com/gnames/gnmatcher/internal/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/io/memio"
+	"github.com/gnames/gnmatcher/pkg/config"
+)
+
+// FixtureName is a name with IDs of data-sources that contain it.
+type FixtureName struct {
+	// Name is a canonical form of a scientific name, or a name of a virus.
+	Name string
+
+	// DataSources contains IDs of data-sources where the name is found.
+	DataSources []int
+}
+
+// Fixture contains lookup data for GNmatcher that works without cache on
+// disk and without gnames database.
+type Fixture struct {
+	// Canonicals are canonical forms of scientific names without ranks,
+	// for example "Bubo bubo" or "Plantago major major".
+	Canonicals []FixtureName
+
+	// Viruses are names of viruses, plasmids, prions etc. Names that go
+	// first have a priority in results.
+	Viruses []FixtureName
+}
+
+// NewWithFixture creates GNmatcher that keeps all lookup data in memory.
+// The data are taken from the fixture, CacheDir and database settings of
+// the configuration are ignored. It is meant for small sets of names, for
+// example for tests. Init still has to be called before matching.
+func NewWithFixture(cfg config.Config, fx Fixture) GNmatcher {
+	data := memio.NewProvider(memioNames(fx.Canonicals), memioNames(fx.Viruses))
+	em := memio.NewExactMatcher(data)
+	fm := memio.NewFuzzyMatcher(data)
+	vm := memio.NewVirusMatcher(data)
+	return gnmatcher{
+		cfg:      cfg,
+		matcher:  matcher.NewMatcher(em, fm, vm, cfg),
+		reload:   &reloadState{},
+		inMemory: true,
+	}
+}
+
+func memioNames(names []FixtureName) []memio.Name {
+	res := make([]memio.Name, len(names))
+	for i, v := range names {
+		res[i] = memio.Name{Name: v.Name, DataSources: v.DataSources}
+	}
+	return res
+}
@@ -0,0 +1,50 @@
+package gnmatcher_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	gnmatcher "github.com/gnames/gnmatcher/pkg"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithFixture(t *testing.T) {
+	assert := assert.New(t)
+	fx := gnmatcher.Fixture{
+		Canonicals: []gnmatcher.FixtureName{
+			{Name: "Bubo bubo", DataSources: []int{1, 3}},
+			{Name: "Pardosa moesta", DataSources: []int{3}},
+		},
+		Viruses: []gnmatcher.FixtureName{
+			{Name: "Tobacco mosaic virus", DataSources: []int{1}},
+			{Name: "Tobacco mosaic virus", DataSources: []int{4}},
+		},
+	}
+	cfg := config.New(config.OptCacheDir(t.TempDir()))
+	gnm := gnmatcher.NewWithFixture(cfg, fx)
+	assert.Nil(gnm.Init())
+
+	names := []string{
+		"Bubo bubo", "Pardosa maesta", "Tobacco mosaic virus", "Pardosa lugubris",
+	}
+	res := gnm.MatchNames(names)
+	assert.Equal(vlib.Exact, res.Matches[0].MatchType)
+	assert.Equal([]int{1, 3}, res.Matches[0].MatchItems[0].DataSources)
+	assert.Equal(vlib.Fuzzy, res.Matches[1].MatchType)
+	assert.Equal("Pardosa moesta", res.Matches[1].MatchItems[0].MatchStr)
+	assert.Equal(vlib.Virus, res.Matches[2].MatchType)
+	assert.Equal([]int{1, 4}, res.Matches[2].MatchItems[0].DataSources)
+	assert.Equal(vlib.NoMatch, res.Matches[3].MatchType)
+
+	num, err := gnm.Update(filepath.Join(dumpDir, "delta"))
+	assert.Nil(err)
+	assert.Equal(2, num)
+	res = gnm.MatchNames(names)
+	assert.Equal(vlib.Exact, res.Matches[3].MatchType)
+
+	err = gnm.Reload()
+	assert.True(errors.Is(err, gnmatcher.ErrReloadInMemory))
+}
@@ -1,34 +1,68 @@
 package gnmatcher
 
 import (
+	"context"
+
 	"github.com/gnames/gnlib/ent/gnvers"
 	mlib "github.com/gnames/gnlib/ent/matcher"
 	"github.com/gnames/gnmatcher/internal/ent/matcher"
+	"github.com/gnames/gnmatcher/internal/ent/provider"
 	"github.com/gnames/gnmatcher/internal/io/bloom"
+	"github.com/gnames/gnmatcher/internal/io/dumpio"
+	"github.com/gnames/gnmatcher/internal/io/pgio"
 	"github.com/gnames/gnmatcher/internal/io/trie"
 	"github.com/gnames/gnmatcher/internal/io/virusio"
 	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
 )
 
 // gnmatcher implements GNmatcher interface.
 type gnmatcher struct {
 	cfg     config.Config
 	matcher matcher.Matcher
+	reload  *reloadState
+
+	// inMemory is true if lookup data are kept in memory and do not use
+	// the cache.
+	inMemory bool
 }
 
 // New creates a GNmatcher from config. It wires internal components but
 // performs no I/O. Call Init() to load caches and connect to the database.
 func New(cfg config.Config) GNmatcher {
-	em := bloom.New(cfg)
-	fm := trie.New(cfg)
-	vm := virusio.New(cfg)
+	data := newDataProvider(cfg)
+	em := bloom.New(cfg, data)
+	fm := trie.New(cfg, data)
+	vm := virusio.New(cfg, data)
 	return gnmatcher{
 		cfg:     cfg,
 		matcher: matcher.NewMatcher(em, fm, vm, cfg),
+		reload:  &reloadState{dir: cfg.CacheDir},
+	}
+}
+
+// newDataProvider returns a source of data for building lookup caches.
+// If DumpDir is set, data come from the local dump, otherwise from gnames
+// PostgreSQL database.
+func newDataProvider(cfg config.Config) provider.DataProvider {
+	if cfg.DumpDir != "" {
+		return dumpio.New(cfg.DumpDir)
 	}
+	return pgio.New(cfg)
 }
 
 func (gnm gnmatcher) Init() error {
+	if gnm.inMemory {
+		return gnm.matcher.Init()
+	}
+	err := promoteGeneration(gnm.cfg)
+	if err != nil {
+		return err
+	}
+	err = checkCache(gnm.cfg)
+	if err != nil {
+		return err
+	}
 	return gnm.matcher.Init()
 }
 
@@ -36,6 +70,39 @@ func (gnm gnmatcher) MatchNames(names []string, opts ...config.Option) mlib.Outp
 	return gnm.matcher.MatchNames(names, opts...)
 }
 
+func (gnm gnmatcher) MatchNamesCtx(
+	ctx context.Context,
+	names []string,
+	opts ...config.Option,
+) (mlib.Output, error) {
+	return gnm.matcher.MatchNamesCtx(ctx, names, opts...)
+}
+
+func (gnm gnmatcher) MatchNamesDetailed(
+	ctx context.Context,
+	names []string,
+	opts ...config.Option,
+) (output.Output, error) {
+	return gnm.matcher.MatchNamesDetailed(ctx, names, opts...)
+}
+
+func (gnm gnmatcher) MatchStream(
+	ctx context.Context,
+	chNames <-chan string,
+	opts ...config.Option,
+) <-chan output.Match {
+	return gnm.matcher.MatchStream(ctx, chNames, opts...)
+}
+
+func (gnm gnmatcher) Close() error {
+	// lookup data are not unlocked, because they cannot be changed after
+	// Close.
+	if err := gnm.reload.lock(opClose); err != nil {
+		return err
+	}
+	return gnm.matcher.Close()
+}
+
 func (gnm gnmatcher) GetVersion() gnvers.Version {
 	return gnvers.Version{Version: Version, Build: Build}
 }
@@ -1,10 +1,12 @@
 package gnmatcher_test
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"testing"
 
+	vlib "github.com/gnames/gnlib/ent/verifier"
 	gnmatcher "github.com/gnames/gnmatcher/pkg"
 	"github.com/gnames/gnmatcher/pkg/config"
 	"github.com/stretchr/testify/assert"
@@ -19,6 +21,26 @@ func TestVersion(t *testing.T) {
 	assert.Equal(t, "n/a", ver.Build)
 }
 
+// TestClose checks that GNmatcher cannot change lookup data after Close.
+func TestClose(t *testing.T) {
+	assert := assert.New(t)
+	fx := gnmatcher.Fixture{
+		Canonicals: []gnmatcher.FixtureName{
+			{Name: "Bubo bubo", DataSources: []int{1}},
+		},
+	}
+	gnm := gnmatcher.NewWithFixture(config.New(), fx)
+	assert.Nil(gnm.Init())
+	res, err := gnm.MatchNamesCtx(context.Background(), []string{"Bubo bubo"})
+	assert.Nil(err)
+	assert.Equal(vlib.Exact, res.Matches[0].MatchType)
+
+	assert.Nil(gnm.Close())
+	_, err = gnm.Update(t.TempDir())
+	assert.ErrorIs(err, gnmatcher.ErrBusy)
+	assert.Equal(gnmatcher.BusyError{Op: "close"}, err)
+}
+
 func Example() {
 	// Note that it takes several minutes to initialize lookup data structures.
 	// Requirement for initialization: Postgresql database with loaded
@@ -32,6 +54,7 @@ func Example() {
 		fmt.Println(err)
 		return
 	}
+	defer gnm.Close()
 	res := gnm.MatchNames([]string{"Pomatomus saltator", "Pardosa moesta"})
 	for _, match := range res.Matches {
 		fmt.Println(match.Name)
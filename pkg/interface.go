@@ -11,15 +11,20 @@
 package gnmatcher
 
 import (
+	"context"
+
 	"github.com/gnames/gnlib/ent/gnvers"
 	mlib "github.com/gnames/gnlib/ent/matcher"
 	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/gnames/gnmatcher/pkg/output"
 )
 
 // GNmatcher is a public API to the project functionality.
 type GNmatcher interface {
 	// Init loads data from cache on disk, and, if cache is empty, populates it
 	// from gnames database. Must be called once after New before any matching.
+	// Cache components that are incomplete, modified, or do not correspond
+	// to the cache manifest are rebuilt before loading.
 	Init() error
 
 	// MatchNames takes a slice of scientific name-strings with options and
@@ -37,9 +42,82 @@ type GNmatcher interface {
 	// to be found in a database.
 	//
 	// The resulting output does provide canonical forms, but not the sources
-	// where they are registered.
+	// where they are registered. Match items are sorted by their scores,
+	// that are returned by MatchNamesDetailed.
+	//
+	// Match types of the output are limited to types of gnlib. Extended
+	// match types, like AbbreviatedGenus, and details of match items are
+	// returned only by MatchNamesDetailed. For example, a name with an
+	// abbreviated genus gets a Fuzzy match here, InputStr of its items
+	// keeps the abbreviation, like 'P. saltatrix', and EditDistance counts
+	// the restored letters of the genus. Approximate matches of
+	// viruses (ApproximateVirus) get Virus match type, and their items have
+	// EditDistance above 0, while viruses found by their beginning have 0.
 	MatchNames(names []string, opts ...config.Option) mlib.Output
 
+	// MatchNamesCtx works like MatchNames, but stops matching as soon as the
+	// context is canceled or its deadline is exceeded. In such a case it
+	// returns partial results together with the context error. Names that
+	// were not processed receive NoMatch match type.
+	MatchNamesCtx(
+		ctx context.Context,
+		names []string,
+		opts ...config.Option,
+	) (mlib.Output, error)
+
+	// MatchNamesDetailed works like MatchNamesCtx, but returns detailed
+	// output. If matching of a name-string failed, its match has NoMatch
+	// match type and contains the description of the error. The returned
+	// error is not nil if the context was canceled, or if matching of at
+	// least one name-string failed. Results are returned in both cases,
+	// names that were not processed because of canceled context have the
+	// context error, and ErrorsNum counts all failed names.
+	MatchNamesDetailed(
+		ctx context.Context,
+		names []string,
+		opts ...config.Option,
+	) (output.Output, error)
+
+	// MatchStream takes name-strings from the input channel and sends their
+	// matches to the returned channel, keeping the order of the input.
+	// Unlike MatchNames, it does not limit the number of names, so it can
+	// be used to match very large lists with constant memory. If results
+	// are not read, reading of the input stops as well. The returned channel
+	// is closed after the input channel is closed and all names are matched,
+	// or when the context is canceled. If matching of a name-string failed,
+	// its result contains the error.
+	MatchStream(
+		ctx context.Context,
+		chNames <-chan string,
+		opts ...config.Option,
+	) <-chan output.Match
+
+	// Reload builds a new generation of lookup data from the current data
+	// source in a directory next to the CacheDir, and replaces lookup data
+	// of the running matcher with it. Requests that started before the
+	// replacement finish with the previous data, which are released
+	// afterwards. On the next start the new generation is moved to the
+	// CacheDir. If another reload or update is running, it returns
+	// BusyError (see ErrBusy) without waiting.
+	Reload() error
+
+	// Update adds names from a delta to the lookup data of the running
+	// matcher, and saves them to the cache. The delta is a directory in the
+	// format of a dump of gnames data (see DumpDir), that contains only
+	// 'canonicals.tsv' and 'name_string_indices.tsv' files with new
+	// canonical forms and their data-sources. Exact matching of new names
+	// works right away, their fuzzy matching uses a supplementary index
+	// until the next full rebuild of the cache. It returns the number of
+	// new stems. If a reload or another update is running, it returns
+	// BusyError (see ErrBusy) without waiting.
+	Update(deltaDir string) (int, error)
+
+	// Close waits until running requests are finished, stops matching
+	// workers and releases lookup data. GNmatcher cannot be used after
+	// Close. Reloads and updates that start afterwards return BusyError.
+	// If a reload or an update is running, Close returns BusyError.
+	Close() error
+
 	// GetConfig provides configuration object of GNmatcher.
 	GetConfig() config.Config
 
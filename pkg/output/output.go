@@ -0,0 +1,240 @@
+// package output contains detailed results of name-strings matching. The
+// results extend matcher.Output from gnlib with data that are specific to
+// gnmatcher, and can be converted back to matcher.Output when such data
+// are not needed.
+package output
+
+import (
+	"time"
+
+	mlib "github.com/gnames/gnlib/ent/matcher"
+)
+
+// Output is the result of matching of a batch of name-strings.
+type Output struct {
+	// Meta contains metadata of the request.
+	mlib.Meta `json:"metadata"`
+
+	// ErrorsNum is the number of name-strings that could not be matched
+	// because of errors.
+	ErrorsNum int `json:"errorsNum,omitempty"`
+
+	// Matches contain results of matching in the same order as the input.
+	Matches []Match `json:"matches"`
+}
+
+// Match is the result of matching of one name-string.
+type Match struct {
+	mlib.Match
+
+	// ExtendedMatchType is a match type that is specific to gnmatcher, and
+	// cannot be expressed by MatchType of gnlib. In such cases MatchType
+	// contains the closest match type of gnlib. It is empty for other
+	// matches.
+	ExtendedMatchType string `json:"extendedMatchType,omitempty"`
+
+	// ItemsDetails contain data of MatchItems that are specific to
+	// gnmatcher. They are given in the same order as MatchItems.
+	ItemsDetails []ItemDetails `json:"matchItemsDetails,omitempty"`
+
+	// Explanation describes how the name-string was matched. It is given
+	// only if explain mode was requested.
+	Explanation *Explanation `json:"explanation,omitempty"`
+
+	// Error is not empty if matching of the name-string failed. In this
+	// case the match might be incomplete, or have NoMatch match type.
+	Error string `json:"error,omitempty"`
+}
+
+// MatcherOutput converts Output to matcher.Output of gnlib.
+func (o Output) MatcherOutput() mlib.Output {
+	res := mlib.Output{
+		Meta:    o.Meta,
+		Matches: make([]mlib.Match, len(o.Matches)),
+	}
+	for i := range o.Matches {
+		res.Matches[i] = o.Matches[i].Match
+	}
+	return res
+}
+
+// ItemDetails contains data of a match item that are specific to gnmatcher.
+type ItemDetails struct {
+	// Score is a number from 0 to 1 that estimates how likely the match item
+	// is the name meant by the input. It combines match type, partial-match
+	// level, edit distances, agreement of cardinalities and the number of
+	// data-sources of the item. Match items are sorted by their scores.
+	Score float64 `json:"score"`
+
+	// WordsChange is not empty if the item was found after merging two
+	// words of the input (WordsMerged), or splitting one of its words
+	// (WordsSplit).
+	WordsChange string `json:"wordsChange,omitempty"`
+
+	// GenusChange is not empty if the item has the same epithets as the
+	// input, but a different genus. The genus can be misspelled
+	// (GenusMisspelled), or the name was moved to another genus
+	// (GenusRecombined).
+	GenusChange string `json:"genusChange,omitempty"`
+
+	// GenusInferred is true if the input has an abbreviated genus, like
+	// 'P. saltatrix', and the full genus of the item was inferred from
+	// other names of the request, or from the index of epithets.
+	GenusInferred bool `json:"genusInferred,omitempty"`
+
+	// RemovedWords are the words of the canonical form that were removed
+	// by partial matching to get the input of the item.
+	RemovedWords []string `json:"removedWords,omitempty"`
+
+	// SpeciesGroup tells how an item of the species group is related to
+	// the input. It can be an autonym of a binomial (SpeciesGroupAutonym),
+	// the parent species of an infraspecific name (SpeciesGroupParent), or
+	// its sibling infraspecific taxon (SpeciesGroupSibling).
+	SpeciesGroup string `json:"speciesGroup,omitempty"`
+
+	// HybridParent is the number of the parent of a hybrid formula,
+	// starting from 1, that was matched by the item. It is 0 for names
+	// that are not hybrid formulas.
+	HybridParent int `json:"hybridParent,omitempty"`
+
+	// VirusMatch is not empty if a virus name was found approximately. Its
+	// tokens can have a few edits (VirusFuzzy), or be in a different order,
+	// or the virus name can have extra tokens (VirusTokenSet).
+	VirusMatch string `json:"virusMatch,omitempty"`
+
+	// ContextBoost is true if the score of the item was increased, because
+	// its genus is found in exact matches of other names of the request.
+	ContextBoost bool `json:"contextBoost,omitempty"`
+}
+
+// Values of ExtendedMatchType field of Match.
+const (
+	// AbbreviatedGenus means that the input has an abbreviated genus, and
+	// match items have genera inferred from the abbreviation. MatchType of
+	// such matches is Fuzzy.
+	AbbreviatedGenus = "AbbreviatedGenus"
+
+	// HybridFormula means that the input is a hybrid formula, like 'Salix
+	// alba × Salix fragilis', that was not found as a whole, and its
+	// parents were matched separately. MatchType of such matches is
+	// PartialExact or PartialFuzzy, and ItemsDetails tell which parent
+	// was matched by an item.
+	HybridFormula = "HybridFormula"
+
+	// ApproximateVirus means that the virus name was not found by its
+	// beginning, and match items were found by fuzzy or token-set matching.
+	// MatchType of such matches is Virus, and ItemsDetails tell how an
+	// item was matched.
+	ApproximateVirus = "ApproximateVirus"
+
+	// Recombination means that match items were found by epithets of the
+	// input, and they belong to another genus (see GenusRecombined).
+	// MatchType of such matches is Fuzzy.
+	Recombination = "Recombination"
+)
+
+// Values of WordsChange field of ItemDetails.
+const (
+	// WordsMerged means that two adjacent words of the input were merged,
+	// for example 'Pomato mus saltatrix' became 'Pomatomus saltatrix'.
+	WordsMerged = "merged"
+
+	// WordsSplit means that a word of the input was split in two words,
+	// for example 'Aus buscus' became 'Aus bus cus'.
+	WordsSplit = "split"
+)
+
+// Values of GenusChange field of ItemDetails.
+const (
+	// GenusMisspelled means that genera of the input and the item differ
+	// by a few edits, for example 'Pomatomos saltatrix' and 'Pomatomus
+	// saltatrix'.
+	GenusMisspelled = "misspelled"
+
+	// GenusRecombined means that genera of the input and the item are
+	// different, for example 'Aus bus' and 'Cus bus'.
+	GenusRecombined = "recombination"
+)
+
+// Values of SpeciesGroup field of ItemDetails.
+const (
+	// SpeciesGroupAutonym means that the item is an autonym of the
+	// binomial input, for example 'Aus bus var. bus' for 'Aus bus'.
+	SpeciesGroupAutonym = "autonym"
+
+	// SpeciesGroupParent means that the item is the parent species of the
+	// infraspecific input, for example 'Aus bus' for 'Aus bus cus'.
+	SpeciesGroupParent = "parentSpecies"
+
+	// SpeciesGroupSibling means that the item is another infraspecific
+	// taxon of the same species, for example 'Aus bus dus' for
+	// 'Aus bus cus'.
+	SpeciesGroupSibling = "sibling"
+)
+
+// Values of VirusMatch field of ItemDetails.
+const (
+	// VirusFuzzy means that tokens of the virus name differ from tokens of
+	// the input by a few edits, for example 'Tobaco mosaic virus' and
+	// 'Tobacco mosaic virus'.
+	VirusFuzzy = "fuzzy"
+
+	// VirusTokenSet means that tokens of the input were found in the virus
+	// name in a different order, or the virus name has extra tokens, for
+	// example 'mosaic virus tobacco' and 'Tobacco mosaic virus'.
+	VirusTokenSet = "tokenSet"
+)
+
+// Explanation describes how a name-string was matched.
+type Explanation struct {
+	// Canonical is the simple canonical form of the parsed name-string.
+	Canonical string `json:"canonical,omitempty"`
+
+	// CanonicalStem is the stemmed canonical form.
+	CanonicalStem string `json:"canonicalStem,omitempty"`
+
+	// Cardinality is the number of elements of the name: 1 for uninomials,
+	// 2 for binomials etc. It is 0 if the name-string was not parsed.
+	Cardinality int `json:"cardinality"`
+
+	// IsVirus is true if the name-string looks like a name of a virus.
+	IsVirus bool `json:"isVirus,omitempty"`
+
+	// Stages are matching stages in the order they were attempted.
+	Stages []Stage `json:"stages"`
+
+	// Duration is the time spent on matching of the name-string in
+	// nanoseconds.
+	Duration time.Duration `json:"durationNs"`
+}
+
+// Stage describes one attempted matching stage.
+type Stage struct {
+	// Name is the name of the stage, for example 'exactStem', 'fuzzy' or
+	// 'partial'.
+	Name string `json:"name"`
+
+	// Input is the string the stage tried to match.
+	Input string `json:"input,omitempty"`
+
+	// Candidates are stems that were found by fuzzy matching.
+	Candidates []string `json:"candidates,omitempty"`
+
+	// Rejected are candidates that were found, but did not pass checks.
+	Rejected []Rejection `json:"rejected,omitempty"`
+
+	// MatchItemsNum is the number of match items found by the stage.
+	MatchItemsNum int `json:"matchItemsNum"`
+
+	// Duration is the time spent on the stage in nanoseconds.
+	Duration time.Duration `json:"durationNs"`
+}
+
+// Rejection describes a candidate that was rejected by a matching stage.
+type Rejection struct {
+	// Candidate is a rejected stem or matched string.
+	Candidate string `json:"candidate"`
+
+	// Reason explains why the candidate was rejected.
+	Reason string `json:"reason"`
+}
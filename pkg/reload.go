@@ -0,0 +1,192 @@
+package gnmatcher
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gnames/gnmatcher/internal/io/bloom"
+	"github.com/gnames/gnmatcher/internal/io/manifest"
+	"github.com/gnames/gnmatcher/internal/io/trie"
+	"github.com/gnames/gnmatcher/internal/io/virusio"
+	"github.com/gnames/gnmatcher/pkg/config"
+)
+
+// ErrBusy is returned by Reload and Update if lookup data are being changed
+// by another reload or update. The returned error is a BusyError that
+// tells which operation is running, errors.Is(err, ErrBusy) is true for it.
+var ErrBusy = errors.New("lookup data are being changed")
+
+// BusyError is returned by Reload and Update if another operation changes
+// lookup data.
+type BusyError struct {
+	// Op is the running operation, "reload", "update" or "close".
+	Op string
+}
+
+func (e BusyError) Error() string {
+	return fmt.Sprintf("%s of lookup data is in progress", e.Op)
+}
+
+// Is makes BusyError match ErrBusy.
+func (e BusyError) Is(target error) bool {
+	return target == ErrBusy
+}
+
+// Operations that change lookup data.
+const (
+	opReload = "reload"
+	opUpdate = "update"
+	opClose  = "close"
+)
+
+// ErrReloadInMemory is returned by Reload if lookup data are not in the
+// cache, but were given by a fixture.
+var ErrReloadInMemory = errors.New("lookup data from a fixture cannot be reloaded")
+
+// genSuffix separates the CacheDir path from the timestamp of a new
+// generation of lookup data. Generations are created next to the CacheDir.
+const genSuffix = ".gen-"
+
+// reloadState is shared by all copies of gnmatcher.
+type reloadState struct {
+	// mu guards op.
+	mu sync.Mutex
+
+	// op is the operation that changes lookup data now, it is empty if
+	// there is no such operation.
+	op string
+
+	// dir is the directory with the lookup data that are used now. It is
+	// changed only by the running operation.
+	dir string
+}
+
+// lock reserves lookup data for the operation. Reloads and updates change
+// the same data, so they cannot run at the same time. If another operation
+// is running, lock does not wait for it and returns BusyError.
+func (rs *reloadState) lock(op string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.op != "" {
+		return BusyError{Op: rs.op}
+	}
+	rs.op = op
+	return nil
+}
+
+// unlock releases lookup data reserved by lock.
+func (rs *reloadState) unlock() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.op = ""
+}
+
+func (gnm gnmatcher) Reload() error {
+	if gnm.inMemory {
+		return ErrReloadInMemory
+	}
+	if err := gnm.reload.lock(opReload); err != nil {
+		return err
+	}
+	defer gnm.reload.unlock()
+
+	cfg := gnm.cfg
+	cfg.CacheDir = gnm.cfg.CacheDir + genSuffix +
+		time.Now().UTC().Format("20060102T150405.000000000")
+	slog.Info("Building new lookup data", "path", cfg.CacheDir)
+	err := BuildCache(cfg)
+	if err != nil {
+		_ = os.RemoveAll(cfg.CacheDir)
+		return fmt.Errorf("cannot build new lookup data: %w", err)
+	}
+
+	data := newDataProvider(cfg)
+	em := bloom.New(cfg, data)
+	fm := trie.New(cfg, data)
+	vm := virusio.New(cfg, data)
+	released, err := gnm.matcher.Reload(em, fm, vm)
+	if err != nil {
+		_ = os.RemoveAll(cfg.CacheDir)
+		return fmt.Errorf("cannot load new lookup data: %w", err)
+	}
+	slog.Info("Lookup data are reloaded", "path", cfg.CacheDir)
+
+	oldDir := gnm.reload.dir
+	gnm.reload.dir = cfg.CacheDir
+	go func() {
+		<-released
+		// The CacheDir is replaced by the latest generation on the next
+		// start, other generations are not needed anymore.
+		if oldDir != gnm.cfg.CacheDir {
+			_ = os.RemoveAll(oldDir)
+		}
+		slog.Info("Previous lookup data are released", "path", oldDir)
+	}()
+	return nil
+}
+
+// promoteGeneration moves the latest complete generation of lookup data,
+// created by Reload, to the CacheDir, so reloaded data survive restarts.
+// Other generations are removed.
+func promoteGeneration(cfg config.Config) error {
+	dirs, err := filepath.Glob(cfg.CacheDir + genSuffix + "*")
+	if err != nil || len(dirs) == 0 {
+		return err
+	}
+	slices.Sort(dirs)
+
+	var latest string
+	for _, v := range slices.Backward(dirs) {
+		if isComplete(v) {
+			latest = v
+			break
+		}
+	}
+
+	if latest != "" {
+		slog.Info("Using reloaded lookup data", "path", latest)
+		oldDir := cfg.CacheDir + ".old"
+		if err = os.RemoveAll(oldDir); err != nil {
+			return err
+		}
+		err = os.Rename(cfg.CacheDir, oldDir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err = os.Rename(latest, cfg.CacheDir); err != nil {
+			return err
+		}
+		dirs = append(dirs, oldDir)
+	}
+
+	for _, v := range dirs {
+		if v == latest {
+			continue
+		}
+		if err = os.RemoveAll(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isComplete checks if all components of the cache in the directory were
+// built.
+func isComplete(dir string) bool {
+	mf, err := manifest.Load(dir)
+	if err != nil || mf.FormatVersion != manifest.FormatVersion {
+		return false
+	}
+	for _, v := range CacheComponents {
+		if _, ok := mf.Components[string(v)]; !ok {
+			return false
+		}
+	}
+	return true
+}
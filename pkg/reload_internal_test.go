@@ -0,0 +1,77 @@
+package gnmatcher
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gnames/gnmatcher/internal/io/manifest"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPromoteGeneration checks that the latest complete generation
+// replaces the CacheDir, and other generations are removed.
+func TestPromoteGeneration(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(
+		config.OptCacheDir(filepath.Join(t.TempDir(), "cache")),
+	)
+	complete := manifest.Manifest{
+		FormatVersion: manifest.FormatVersion,
+		Components:    make(map[string]manifest.Component),
+	}
+	for _, v := range CacheComponents {
+		complete.Components[string(v)] = manifest.Component{}
+	}
+	incomplete := manifest.Manifest{FormatVersion: manifest.FormatVersion}
+
+	mfs := map[string]manifest.Manifest{
+		cfg.CacheDir: complete,
+		cfg.CacheDir + genSuffix + "20260101T000000": complete,
+		cfg.CacheDir + genSuffix + "20260102T000000": complete,
+		cfg.CacheDir + genSuffix + "20260103T000000": incomplete,
+	}
+	for k, v := range mfs {
+		assert.Nil(os.MkdirAll(k, 0755))
+		assert.Nil(v.Save(k))
+		assert.Nil(os.WriteFile(filepath.Join(k, "id"), []byte(k), 0644))
+	}
+
+	assert.Nil(promoteGeneration(cfg))
+	id, err := os.ReadFile(filepath.Join(cfg.CacheDir, "id"))
+	assert.Nil(err)
+	assert.Equal(cfg.CacheDir+genSuffix+"20260102T000000", string(id))
+
+	gens, err := filepath.Glob(cfg.CacheDir + genSuffix + "*")
+	assert.Nil(err)
+	assert.Empty(gens)
+	_, err = os.Stat(cfg.CacheDir + ".old")
+	assert.True(os.IsNotExist(err))
+}
+
+// TestReloadBusy checks that reloads and updates do not wait for each
+// other, and tell which operation is running.
+func TestReloadBusy(t *testing.T) {
+	assert := assert.New(t)
+	gnm := gnmatcher{reload: &reloadState{}}
+
+	assert.Nil(gnm.reload.lock(opUpdate))
+	err := gnm.Reload()
+	assert.ErrorIs(err, ErrBusy)
+	assert.Equal(BusyError{Op: opUpdate}, err)
+	gnm.reload.unlock()
+
+	assert.Nil(gnm.reload.lock(opReload))
+	_, err = gnm.Update(t.TempDir())
+	assert.ErrorIs(err, ErrBusy)
+	assert.Equal("reload of lookup data is in progress", err.Error())
+	gnm.reload.unlock()
+
+	assert.Nil(gnm.reload.lock(opReload))
+	gnm.reload.unlock()
+}
@@ -0,0 +1,63 @@
+package gnmatcher_test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	gnmatcher "github.com/gnames/gnmatcher/pkg"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	dump := t.TempDir()
+	assert.Nil(os.CopyFS(dump, os.DirFS(dumpDir)))
+	cfg := config.New(
+		config.OptCacheDir(filepath.Join(t.TempDir(), "cache")),
+		config.OptDumpDir(dump),
+	)
+	gnm := gnmatcher.New(cfg)
+	assert.Nil(gnm.Init())
+
+	names := []string{"Tobacco necrosis virus"}
+	res := gnm.MatchNames(names)
+	assert.Equal(vlib.NoMatch, res.Matches[0].MatchType)
+
+	f, err := os.OpenFile(
+		filepath.Join(dump, "viruses.tsv"), os.O_APPEND|os.O_WRONLY, 0644,
+	)
+	assert.Nil(err)
+	_, err = f.WriteString("\tTobacco necrosis virus\t4\n")
+	assert.Nil(err)
+	assert.Nil(f.Close())
+
+	assert.Nil(gnm.Reload())
+	res = gnm.MatchNames(names)
+	assert.Equal(vlib.Virus, res.Matches[0].MatchType)
+
+	assert.Nil(gnm.Reload())
+	res = gnm.MatchNames(names)
+	assert.Equal(vlib.Virus, res.Matches[0].MatchType)
+
+	// the first generation is removed after it is released.
+	var gens []string
+	for range 50 {
+		gens, err = filepath.Glob(cfg.CacheDir + ".gen-*")
+		assert.Nil(err)
+		if len(gens) == 1 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.Equal(1, len(gens))
+	_, err = os.Stat(cfg.CacheDir)
+	assert.Nil(err)
+}
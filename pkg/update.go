@@ -0,0 +1,43 @@
+package gnmatcher
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gnames/gnmatcher/internal/io/dumpio"
+	"github.com/gnames/gnmatcher/internal/io/manifest"
+)
+
+func (gnm gnmatcher) Update(deltaDir string) (int, error) {
+	if err := gnm.reload.lock(opUpdate); err != nil {
+		return 0, err
+	}
+	defer gnm.reload.unlock()
+
+	data := dumpio.New(deltaDir)
+	num, err := gnm.matcher.AddStems(data)
+	if err != nil {
+		return 0, fmt.Errorf("cannot add stems from '%s': %w", deltaDir, err)
+	}
+	if gnm.inMemory {
+		return num, nil
+	}
+
+	dir := gnm.reload.dir
+	mf, err := manifest.Load(dir)
+	if err != nil {
+		return num, err
+	}
+	for _, v := range []CacheComponent{
+		BloomCache, TrieCache, StemsCache, EpithetsCache,
+	} {
+		if err = mf.Update(dir, string(v), num); err != nil {
+			return num, err
+		}
+	}
+	if err = mf.Save(dir); err != nil {
+		return num, fmt.Errorf("cannot save cache manifest: %w", err)
+	}
+	slog.Info("Lookup data are updated", "delta", deltaDir, "newStemsNum", num)
+	return num, nil
+}
@@ -0,0 +1,60 @@
+package gnmatcher_test
+
+import (
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	vlib "github.com/gnames/gnlib/ent/verifier"
+	"github.com/gnames/gnmatcher/internal/io/manifest"
+	gnmatcher "github.com/gnames/gnmatcher/pkg"
+	"github.com/gnames/gnmatcher/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdate(t *testing.T) {
+	assert := assert.New(t)
+	oldLevel := slog.SetLogLoggerLevel(slog.LevelError)
+	defer slog.SetLogLoggerLevel(oldLevel)
+
+	cfg := config.New(
+		config.OptCacheDir(t.TempDir()),
+		config.OptDumpDir(dumpDir),
+	)
+	gnm := gnmatcher.New(cfg)
+	assert.Nil(gnm.Init())
+
+	names := []string{"Pardosa lugubris", "Pardosa lagubris", "Pomatomus saltatrix"}
+	res := gnm.MatchNames(names)
+	assert.Equal(vlib.NoMatch, res.Matches[0].MatchType)
+	assert.Equal(vlib.NoMatch, res.Matches[1].MatchType)
+	assert.Equal([]int{1, 11}, res.Matches[2].MatchItems[0].DataSources)
+
+	num, err := gnm.Update(filepath.Join(dumpDir, "delta"))
+	assert.Nil(err)
+	assert.Equal(1, num)
+
+	res = gnm.MatchNames(names)
+	assert.Equal(vlib.Exact, res.Matches[0].MatchType)
+	assert.Equal([]int{3}, res.Matches[0].MatchItems[0].DataSources)
+	assert.Equal(vlib.Fuzzy, res.Matches[1].MatchType)
+	assert.Equal("Pardosa lugubris", res.Matches[1].MatchItems[0].MatchStr)
+	assert.Equal(vlib.Exact, res.Matches[2].MatchType)
+	assert.Equal([]int{1, 5, 11}, res.Matches[2].MatchItems[0].DataSources)
+
+	// filters are replaced by renaming, no temporary files are left.
+	tmp, err := filepath.Glob(
+		filepath.Join(cfg.CacheDir, string(gnmatcher.BloomCache), "*.tmp"),
+	)
+	assert.Nil(err)
+	assert.Empty(tmp)
+
+	// the manifest stays valid, so the cache is not rebuilt on restart.
+	mf, err := manifest.Load(cfg.CacheDir)
+	assert.Nil(err)
+	assert.Equal(5, mf.Components["trie"].RecordsNum)
+	for _, v := range gnmatcher.CacheComponents {
+		src := mf.Components[string(v)].Source
+		assert.Nil(mf.Check(cfg.CacheDir, string(v), src))
+	}
+}